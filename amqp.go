@@ -0,0 +1,223 @@
+package celery
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// AMQPBroker publishes tasks to a RabbitMQ broker, with publisher confirms.
+// AMQP has no result storage the client could poll, so results are still read
+// from the wrapped backend (the Redis celery result_backend).
+type AMQPBroker struct {
+	BrokerBackend
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func NewAMQPBroker(resultBackend BrokerBackend, url string, queue queueOption) (*AMQPBroker, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to amqp broker; reason: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to open amqp channel; reason: %w", err)
+	}
+
+	// publishes are only done once the broker confirmed it took responsibility for them
+	err = channel.Confirm(false)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to enable amqp publisher confirms; reason: %w", err)
+	}
+
+	if len(queue.Bindings) > 0 {
+		err = declareBindings(channel, queue)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return &AMQPBroker{
+		BrokerBackend: resultBackend,
+		conn:          conn,
+		channel:       channel,
+	}, nil
+}
+
+// declareBindings declares the queue and its exchange (durable, like kombu does),
+// then binds the queue to the exchange with each of its binding routing keys.
+func declareBindings(channel *amqp.Channel, queue queueOption) error {
+	exchangeType := cmp.Or(queue.ExchangeType, "direct")
+	err := channel.ExchangeDeclare(queue.Exchange, exchangeType, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("unable to declare exchange %s; reason: %w", queue.Exchange, err)
+	}
+
+	_, err = channel.QueueDeclare(queue.Name, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("unable to declare queue %s; reason: %w", queue.Name, err)
+	}
+
+	for _, routingKey := range queue.Bindings {
+		err = channel.QueueBind(queue.Name, routingKey, queue.Exchange, false, nil)
+		if err != nil {
+			return fmt.Errorf("unable to bind queue %s to exchange %s with %s; reason: %w", queue.Name, queue.Exchange, routingKey, err)
+		}
+	}
+
+	return nil
+}
+
+func (ab *AMQPBroker) Publish(ctx context.Context, message []byte, rawMessage string, queue string) error {
+	return ab.PublishMany(ctx, [][]byte{message}, queue)
+}
+
+// PublishMany publishes messages to the exchange and routing key of their delivery info,
+// then waits for the broker to confirm all of them. It fails if a message is nacked.
+// Exchanges and queues are expected to be declared by the workers (or with the queue bindings).
+func (ab *AMQPBroker) PublishMany(ctx context.Context, messages [][]byte, queue string) error {
+	confirms := make([]publishConfirm, 0, len(messages))
+	for _, message := range messages {
+		var celeryMessage CeleryMessage
+		err := json.Unmarshal(message, &celeryMessage)
+		if err != nil {
+			return err
+		}
+
+		publishing, err := toPublishing(celeryMessage)
+		if err != nil {
+			return err
+		}
+
+		deliveryInfo := celeryMessage.Properties.DeliveryInfo
+		confirm, err := ab.channel.PublishWithDeferredConfirmWithContext(ctx, deliveryInfo.Exchange, deliveryInfo.RoutingKey, false, false, publishing)
+		if err != nil {
+			return err
+		}
+		confirms = append(confirms, confirm)
+	}
+
+	return waitConfirms(ctx, confirms)
+}
+
+// publishConfirm is the broker confirmation of a published message, an *amqp.DeferredConfirmation.
+type publishConfirm interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+// waitConfirms waits for the broker to confirm each published message.
+// It fails on the first message nacked or not confirmed before ctx is done.
+func waitConfirms(ctx context.Context, confirms []publishConfirm) error {
+	for i, confirm := range confirms {
+		acked, err := confirm.WaitContext(ctx)
+		if err != nil {
+			return fmt.Errorf("amqp publish not confirmed; reason: %w", err)
+		}
+		if !acked {
+			return fmt.Errorf("amqp broker nacked published message %d of %d", i+1, len(confirms))
+		}
+	}
+
+	return nil
+}
+
+// PublishFanout publishes a message to each queue in turn.
+// A failed fanout may have reached the queues published to before the failure.
+func (ab *AMQPBroker) PublishFanout(ctx context.Context, messages map[string][]byte) error {
+	for queue, message := range messages {
+		err := ab.Publish(ctx, message, string(message), queue)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueueLength returns the number of messages ready in the queue.
+// The queue is inspected on its own channel: the broker closes the channel
+// when the queue doesn't exist, which must not break the publishing one.
+func (ab *AMQPBroker) QueueLength(ctx context.Context, queue string) (int64, error) {
+	channel, err := ab.conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("unable to open amqp channel; reason: %w", err)
+	}
+	// already closed by the broker if the inspect failed
+	defer func() { _ = channel.Close() }()
+
+	state, err := channel.QueueInspect(queue)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(state.Messages), nil
+}
+
+// Close closes the amqp connection, then the result backend.
+func (ab *AMQPBroker) Close() error {
+	// closing the connection closes its channel
+	return errors.Join(ab.conn.Close(), ab.BrokerBackend.Close())
+}
+
+func (ab *AMQPBroker) Peek(ctx context.Context, queue string) ([]byte, error) {
+	return nil, errors.New("peeking messages is not supported by the amqp broker")
+}
+
+// toPublishing converts the celery envelope the Redis transport stores as is into an AMQP message,
+// like kombu does: the body is sent decoded, headers and properties as AMQP ones.
+func toPublishing(message CeleryMessage) (amqp.Publishing, error) {
+	body, err := base64.StdEncoding.DecodeString(message.Body)
+	if err != nil {
+		return amqp.Publishing{}, fmt.Errorf("message body is not valid base64; reason: %w", err)
+	}
+
+	return amqp.Publishing{
+		Headers:         toTable(message.Headers),
+		ContentType:     message.ContentType,
+		ContentEncoding: message.ContentEncoding,
+		DeliveryMode:    uint8(message.Properties.DeliveryMode),
+		Priority:        uint8(message.Properties.DeliveryInfo.Priority),
+		CorrelationId:   message.Properties.CorrelationID,
+		ReplyTo:         message.Properties.ReplyTo,
+		Body:            body,
+	}, nil
+}
+
+// toTable converts JSON decoded headers to an AMQP table, nested objects included.
+func toTable(headers map[string]interface{}) amqp.Table {
+	if headers == nil {
+		return nil
+	}
+
+	table := make(amqp.Table, len(headers))
+	for key, value := range headers {
+		table[key] = toTableValue(value)
+	}
+
+	return table
+}
+
+func toTableValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return toTable(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = toTableValue(item)
+		}
+		return values
+	default:
+		return v
+	}
+}
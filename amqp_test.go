@@ -0,0 +1,101 @@
+package celery
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToPublishing(t *testing.T) {
+	t.Parallel()
+
+	message := CeleryMessage{
+		Body: base64.StdEncoding.EncodeToString([]byte(`[[1, 2], {}, {}]`)),
+		Headers: map[string]interface{}{
+			"task":    "tasks.add",
+			"id":      "task",
+			"retries": float64(0),
+			"stamps":  map[string]interface{}{"load": []interface{}{"a", map[string]interface{}{"b": true}}},
+		},
+		ContentType:     "application/json",
+		ContentEncoding: "utf-8",
+		Properties: CeleryProperties{
+			BodyEncoding:  "base64",
+			CorrelationID: "task",
+			DeliveryMode:  2,
+			DeliveryInfo:  CeleryDeliveryInfo{Priority: 7, RoutingKey: "celery", Exchange: "celery"},
+		},
+	}
+
+	publishing, err := toPublishing(message)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`[[1, 2], {}, {}]`), publishing.Body)
+	assert.Equal(t, "application/json", publishing.ContentType)
+	assert.Equal(t, "utf-8", publishing.ContentEncoding)
+	assert.Equal(t, amqp.Persistent, publishing.DeliveryMode)
+	assert.EqualValues(t, 7, publishing.Priority)
+	assert.Equal(t, "task", publishing.CorrelationId)
+	assert.Empty(t, publishing.ReplyTo)
+	assert.Equal(t, amqp.Table{
+		"task":    "tasks.add",
+		"id":      "task",
+		"retries": float64(0),
+		"stamps":  amqp.Table{"load": []interface{}{"a", amqp.Table{"b": true}}},
+	}, publishing.Headers)
+	// nested objects are tables the AMQP encoder accepts
+	assert.NoError(t, publishing.Headers.Validate())
+
+	message.Body = "not base64!"
+	_, err = toPublishing(message)
+	assert.ErrorContains(t, err, "message body is not valid base64")
+}
+
+func TestToTable(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, toTable(nil))
+	assert.Equal(t, amqp.Table{}, toTable(map[string]interface{}{}))
+	assert.Equal(t, amqp.Table{
+		"list": []interface{}{[]interface{}{amqp.Table{"nested": "value"}}},
+	}, toTable(map[string]interface{}{
+		"list": []interface{}{[]interface{}{map[string]interface{}{"nested": "value"}}},
+	}))
+}
+
+// fakeConfirm is a broker confirmation, acked or nacked after a delay.
+type fakeConfirm struct {
+	acked bool
+	delay time.Duration
+}
+
+func (fc fakeConfirm) WaitContext(ctx context.Context) (bool, error) {
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-time.After(fc.delay):
+		return fc.acked, nil
+	}
+}
+
+func TestWaitConfirms(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	require.NoError(t, waitConfirms(ctx, nil))
+	require.NoError(t, waitConfirms(ctx, []publishConfirm{fakeConfirm{acked: true}, fakeConfirm{acked: true}}))
+
+	err := waitConfirms(ctx, []publishConfirm{fakeConfirm{acked: true}, fakeConfirm{}, fakeConfirm{acked: true}})
+	assert.EqualError(t, err, "amqp broker nacked published message 2 of 3")
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err = waitConfirms(ctx, []publishConfirm{fakeConfirm{acked: true, delay: time.Minute}})
+	assert.ErrorContains(t, err, "amqp publish not confirmed")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
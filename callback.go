@@ -0,0 +1,88 @@
+package celery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/redis/go-redis/v9"
+	"go.k6.io/k6/js/modules"
+)
+
+// CallbackBackend reads task results through a JS function registered by the
+// script, so results can be bridged from backends we don't talk to directly
+// (SQL, HTTP...). Publishing is still delegated to the wrapped broker.
+type CallbackBackend struct {
+	BrokerBackend
+	vu       modules.VU
+	resultFn goja.Callable
+}
+
+func NewCallbackBackend(broker BrokerBackend, vu modules.VU, resultFn goja.Callable) *CallbackBackend {
+	return &CallbackBackend{
+		BrokerBackend: broker,
+		vu:            vu,
+		resultFn:      resultFn,
+	}
+}
+
+// Get calls the registered JS function with the task ID.
+// A null/undefined return means the result is not available yet, a string is
+// used as the raw result document and any other value is serialized to JSON.
+func (cb *CallbackBackend) Get(ctx context.Context, taskID string) *redis.StringCmd {
+	rt := cb.vu.Runtime()
+	value, err := cb.resultFn(goja.Undefined(), rt.ToValue(taskID))
+	if err != nil {
+		return redis.NewStringResult("", fmt.Errorf("result callback failed; reason: %w", err))
+	}
+
+	if goja.IsUndefined(value) || goja.IsNull(value) {
+		return redis.NewStringResult("", redis.Nil)
+	}
+
+	if document, ok := value.Export().(string); ok {
+		return redis.NewStringResult(document, nil)
+	}
+
+	document, err := json.Marshal(value.Export())
+	if err != nil {
+		return redis.NewStringResult("", fmt.Errorf("unable to serialize callback result %w", err))
+	}
+
+	return redis.NewStringResult(string(document), nil)
+}
+
+// GetMany calls the registered JS function once per task ID.
+func (cb *CallbackBackend) GetMany(ctx context.Context, taskIDs []string) *redis.SliceCmd {
+	values := make([]interface{}, len(taskIDs))
+	for i, taskID := range taskIDs {
+		document, err := cb.Get(ctx, taskID).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return redis.NewSliceResult(nil, err)
+		}
+		values[i] = document
+	}
+
+	return redis.NewSliceResult(values, nil)
+}
+
+// popCallback removes a function option from the exported options map, as it
+// cannot go through the JSON decoding, and returns it as a goja.Callable.
+func popCallback(rt *goja.Runtime, optionsArg map[string]interface{}, name string) (goja.Callable, error) {
+	value, ok := optionsArg[name]
+	if !ok {
+		return nil, nil
+	}
+	delete(optionsArg, name)
+
+	callback, ok := goja.AssertFunction(rt.ToValue(value))
+	if !ok {
+		return nil, fmt.Errorf("%s must be a function", name)
+	}
+
+	return callback, nil
+}
@@ -0,0 +1,46 @@
+package celery
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackBackendGet(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+	mr := miniredis.RunT(t)
+	vu.run(t, `var calls = [];`)
+	client := vu.newClient(t, "redis://"+mr.Addr(), `{
+		resultMode: "custom",
+		resultFn: (taskID) => {
+			calls.push(taskID);
+			switch (taskID) {
+			case "pending":
+				return null;
+			case "raw":
+				return '{"task_id": "raw", "status": "FAILURE", "result": {"exc_type": "ValueError"}}';
+			default:
+				return { task_id: taskID, status: "SUCCESS", result: { total: 42 } };
+			}
+		},
+	}`)
+
+	result, err := client.GetResult("object")
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", result["status"])
+	assert.Equal(t, map[string]interface{}{"total": float64(42)}, result["result"])
+
+	result, err = client.GetResult("raw")
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", result["status"])
+
+	result, err = client.GetResult("pending")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	assert.Equal(t, []interface{}{"object", "raw", "pending"}, vu.run(t, `calls`))
+}
@@ -2,17 +2,28 @@ package celery
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dop251/goja"
 	"github.com/redis/go-redis/v9"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"github.com/sirupsen/logrus"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/metrics"
 )
 
 func init() {
@@ -61,7 +72,8 @@ type (
 		vu modules.VU
 		// Celery is the exported module instance.
 		*Celery
-		logger logrus.FieldLogger
+		logger  logrus.FieldLogger
+		metrics celeryMetrics
 	}
 )
 
@@ -81,32 +93,71 @@ func New() *CeleryModule {
 func (*CeleryModule) NewModuleInstance(vu modules.VU) modules.Instance {
 
 	logger := vu.InitEnv().Logger.WithField("component", "xk6-celery")
-	return &CeleryInstance{vu: vu, Celery: &Celery{vu: vu}, logger: logger}
+	m, err := registerMetrics(vu)
+	if err != nil {
+		common.Throw(vu.Runtime(), fmt.Errorf("fail to register metrics; reason: %w", err))
+	}
+
+	return &CeleryInstance{vu: vu, Celery: &Celery{vu: vu}, logger: logger, metrics: m}
 }
 
 // Celery is the exported module instance.
 type Celery struct {
 	vu               modules.VU
 	client           ICeleryClient
-	backend          *redis.Client
+	backend          redis.UniversalClient
 	queue            string
 	timeout          time.Duration
 	getRetryInterval time.Duration
+	checkTimeout     time.Duration
+	pollBackoff      float64
+	maxWaitDuration  time.Duration
+	maxMessages      int64
+	published        atomic.Int64
+	resultSchema     *jsonschema.Schema
+	// routeFn is the JS function picking the queue of each submission (if set)
+	routeFn goja.Callable
+	// customResults is set when results are read through a JS callback,
+	// which can't be called outside of the event loop
+	customResults bool
+	// submittedAt records submission time of tasks published by this client
+	submittedAt sync.Map
+	lastError   *lastErrorHook
+	metrics     celeryMetrics
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
 func (mi *CeleryInstance) NewCeleryRedis(call goja.ConstructorCall) *goja.Object {
 	rt := mi.vu.Runtime()
 
+	if len(call.Arguments) == 0 || goja.IsUndefined(call.Arguments[0]) || goja.IsNull(call.Arguments[0]) {
+		common.Throw(rt, errors.New("Redis constructor requires an options object"))
+	}
+
 	var optionsArg map[string]interface{}
 	err := rt.ExportTo(call.Arguments[0], &optionsArg)
 	if err != nil {
 		common.Throw(rt, errors.New("unable to parse options object"))
 	}
 
+	resultFn, err := popCallback(rt, optionsArg, "resultFn")
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+
+	routeFn, err := popCallback(rt, optionsArg, "routeFn")
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+
 	opts, err := newOptionsFrom(optionsArg)
 	if err != nil {
 		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
 	}
+	opts.ResultFn = resultFn
+	opts.RouteFn = routeFn
 
 	opts.applyDefaults()
 	err = opts.validate()
@@ -114,10 +165,64 @@ func (mi *CeleryInstance) NewCeleryRedis(call goja.ConstructorCall) *goja.Object
 		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
 	}
 
-	mi.logger.Infof("configuration %+v", opts)
+	resultSchema, err := compileResultSchema(opts.ResultSchema)
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+
+	opts.tlsConfig, err = newTLSConfig(opts)
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+
+	mi.logger.Infof("configuration %s", opts)
+
+	redisClient, err := NewRedisClient(opts, cmp.Or(opts.PublishPoolSize, opts.PoolSize))
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	resultClient := redisClient
+	if opts.PublishPoolSize > 0 || opts.BackendPoolSize > 0 {
+		// isolate publishes from result polling in separate connection pools
+		resultClient, err = NewRedisClient(opts, cmp.Or(opts.BackendPoolSize, opts.PoolSize))
+		if err != nil {
+			common.Throw(rt, err)
+		}
+	}
+	lastError := &lastErrorHook{}
+	redisClient.AddHook(lastError)
+	if resultClient != redisClient {
+		resultClient.AddHook(lastError)
+	}
+	if opts.CommandMetrics {
+		redisClient.AddHook(&commandMetricsHook{vu: mi.vu, metrics: mi.metrics})
+		if resultClient != redisClient {
+			resultClient.AddHook(&commandMetricsHook{vu: mi.vu, metrics: mi.metrics})
+		}
+	}
+	redisBroker := NewRedisBrokerBackend(redisClient, resultClient, opts)
+	if opts.RequireBackend {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout.Duration)
+		err = redisBroker.Probe(ctx)
+		cancel()
+		if err != nil {
+			common.Throw(rt, fmt.Errorf("celery result backend check failed; reason: %w", err))
+		}
+	}
+
+	var brokerBackend BrokerBackend = redisBroker
+	if opts.Broker == brokerAMQP {
+		amqpBroker, err := NewAMQPBroker(redisBroker, opts.BrokerURL, opts.Queue)
+		if err != nil {
+			common.Throw(rt, fmt.Errorf("fail to innstanciate celery client; reason: %w", err))
+		}
+		brokerBackend = amqpBroker
+	}
+	if opts.ResultMode == resultModeCustom {
+		brokerBackend = NewCallbackBackend(brokerBackend, mi.vu, opts.ResultFn)
+	}
 
-	redisClient := NewRedisClient(opts)
-	client, err := newCeleryClient(redisClient)
+	client, err := newCeleryClient(brokerBackend, opts, mi.vu)
 	if err != nil {
 		common.Throw(rt, fmt.Errorf("fail to innstanciate celery client; reason: %w", err))
 	}
@@ -125,31 +230,131 @@ func (mi *CeleryInstance) NewCeleryRedis(call goja.ConstructorCall) *goja.Object
 	CeleryClient := &Celery{
 		vu:               mi.vu,
 		client:           client,
-		backend:          redisClient,
-		queue:            opts.Queue,
+		backend:          resultClient,
+		queue:            opts.Queue.Name,
 		timeout:          opts.Timeout.Duration,
 		getRetryInterval: opts.GetRetryInterval.Duration,
+		checkTimeout:     opts.CheckTimeout.Duration,
+		pollBackoff:      opts.PollBackoff,
+		maxWaitDuration:  opts.MaxWaitDuration.Duration,
+		maxMessages:      opts.MaxMessages,
+		resultSchema:     resultSchema,
+		routeFn:          opts.RouteFn,
+		customResults:    opts.ResultMode == resultModeCustom,
+		lastError:        lastError,
+		metrics:          mi.metrics,
 	}
 
 	return rt.ToValue(CeleryClient).ToObject(rt)
 }
 
 type options struct {
-	Url              string   `json:"url,omitempty"`
-	SentinelAddrs    []string `json:"addrs,omitempty"`
-	MasterName       string   `json:"mastername,omitempty"`
-	Queue            string   `json:"queue,omitempty"`
-	Timeout          Duration `json:"timeout,omitempty"`
-	GetRetryInterval Duration `json:"getinterval,omitempty"`
+	Url           string      `json:"url,omitempty"`
+	SentinelAddrs []string    `json:"addrs,omitempty"`
+	ClusterAddrs  []string    `json:"clusterAddrs,omitempty"`
+	MasterName    string      `json:"mastername,omitempty"`
+	Queue         queueOption `json:"queue,omitempty"`
+	Timeout       Duration    `json:"timeout,omitempty"`
+	// GetRetryInterval is a pointer to tell an explicit zero interval from an unset one
+	GetRetryInterval        *Duration       `json:"getinterval,omitempty"`
+	CheckTimeout            Duration        `json:"checkTimeout,omitempty"`
+	ResultMode              string          `json:"resultMode,omitempty"`
+	MaxMessages             int64           `json:"maxMessages,omitempty"`
+	ExpiresIn               Duration        `json:"expiresIn,omitempty"`
+	Serializer              string          `json:"serializer,omitempty"`
+	CommandMetrics          bool            `json:"commandMetrics,omitempty"`
+	MaxResultBytes          int             `json:"maxResultBytes,omitempty"`
+	MaxResultMode           string          `json:"maxResultMode,omitempty"`
+	ResultSchema            json.RawMessage `json:"resultSchema,omitempty"`
+	RequireBackend          bool            `json:"requireBackend,omitempty"`
+	CorrelationEqualsTaskID bool            `json:"correlationEqualsTaskId,omitempty"`
+	SequenceHeader          string          `json:"sequenceHeader,omitempty"`
+	BackendShape            string          `json:"backendShape,omitempty"`
+	MinimalProperties       bool            `json:"minimalProperties,omitempty"`
+	PublishMaxRetries       int             `json:"publishMaxRetries,omitempty"`
+	PublishBackoffMax       Duration        `json:"publishBackoffMax,omitempty"`
+	ExchangeType            string          `json:"exchangeType,omitempty"`
+	PollBackoff             float64         `json:"pollBackoff,omitempty"`
+	ProducerID              string          `json:"producerId,omitempty"`
+	QueueType               string          `json:"queueType,omitempty"`
+	MaxWaitDuration         Duration        `json:"maxWaitDuration,omitempty"`
+	// ResultKeyPrefix is a pointer as an empty prefix is valid
+	ResultKeyPrefix    *string  `json:"resultKeyPrefix,omitempty"`
+	ResultKeyHashtag   bool     `json:"resultKeyHashtag,omitempty"`
+	PublishPoolSize    int      `json:"publishPoolSize,omitempty"`
+	BackendPoolSize    int      `json:"backendPoolSize,omitempty"`
+	QueueTTL           Duration `json:"queueTTL,omitempty"`
+	TLS                bool     `json:"tls,omitempty"`
+	CACertFile         string   `json:"caCertFile,omitempty"`
+	CertFile           string   `json:"certFile,omitempty"`
+	KeyFile            string   `json:"keyFile,omitempty"`
+	InsecureSkipVerify bool     `json:"insecureSkipVerify,omitempty"`
+	VUHeaders          bool     `json:"vuHeaders,omitempty"`
+	VerifyPublish      bool     `json:"verifyPublish,omitempty"`
+	Broker             string   `json:"broker,omitempty"`
+	BrokerURL          string   `json:"brokerUrl,omitempty"`
+	PoolSize           int      `json:"poolSize,omitempty"`
+	MinIdleConns       int      `json:"minIdleConns,omitempty"`
+	MaxRetries         int      `json:"maxRetries,omitempty"`
+	Username           string   `json:"username,omitempty"`
+	Password           string   `json:"password,omitempty"`
+	BigIntArgsAsString bool     `json:"bigIntArgsAsString,omitempty"`
+	// DefaultHeaders values are JSON serializable as options are decoded from JSON
+	DefaultHeaders map[string]interface{} `json:"defaultHeaders,omitempty"`
+
+	// ResultFn is the JS function used to fetch results in custom result mode.
+	ResultFn goja.Callable `json:"-"`
+	// RouteFn is the JS function returning the queue of a submission.
+	RouteFn goja.Callable `json:"-"`
+
+	// tlsConfig is loaded from the TLS options
+	tlsConfig *tls.Config
 }
 
+// minTimeoutIntervals is the minimum ratio between the timeout and the check interval.
+const minTimeoutIntervals = 2
+
+// defaultResultKeyPrefix is the key prefix of celery redis result backend.
+const defaultResultKeyPrefix = "celery-task-meta-"
+
+const (
+	resultModeBackend = "backend"
+	resultModeCustom  = "custom"
+
+	serializerJSON     = "json"
+	serializerProtobuf = "protobuf"
+	serializerMsgpack  = "msgpack"
+	serializerYAML     = "yaml"
+
+	maxResultModeError    = "error"
+	maxResultModeTruncate = "truncate"
+
+	backendShapeDict = "dict"
+	backendShapeBare = "bare"
+
+	queueTypeList = "list"
+	queueTypeSet  = "set"
+
+	brokerRedis = "redis"
+	brokerAMQP  = "amqp"
+)
+
 func (o *options) applyDefaults() {
 	if o.Url == "" {
 		o.Url = "redis://127.0.0.1:6379"
 	}
 
-	if o.Queue == "" {
-		o.Queue = "celery"
+	if o.Queue.Name == "" {
+		o.Queue.Name = "celery"
+	}
+	if o.Queue.Exchange == "" {
+		o.Queue.Exchange = o.Queue.Name
+	}
+	if o.Queue.RoutingKey == "" {
+		o.Queue.RoutingKey = o.Queue.Name
+	}
+	if o.Queue.ExchangeType == "" {
+		o.Queue.ExchangeType = o.ExchangeType
 	}
 	if o.MasterName == "" {
 		o.MasterName = "default-master"
@@ -159,17 +364,117 @@ func (o *options) applyDefaults() {
 		o.Timeout.Duration = 30 * time.Second
 	}
 
-	if o.GetRetryInterval.Duration == 0 {
-		o.GetRetryInterval.Duration = 50 * time.Millisecond
+	if o.GetRetryInterval == nil {
+		o.GetRetryInterval = &Duration{50 * time.Millisecond}
+	}
+
+	if o.QueueType == "" {
+		o.QueueType = queueTypeList
+	}
+
+	if o.ProducerID == "" {
+		o.ProducerID = defaultProducerID()
+	}
+
+	if o.PollBackoff == 0 {
+		o.PollBackoff = 1
+	}
+
+	if o.Broker == "" {
+		o.Broker = brokerRedis
+	}
+
+	if o.ResultKeyPrefix == nil {
+		prefix := defaultResultKeyPrefix
+		o.ResultKeyPrefix = &prefix
+	}
+
+	if o.CheckTimeout.Duration == 0 {
+		o.CheckTimeout.Duration = min(time.Second, o.Timeout.Duration)
+	}
+
+	if o.PublishBackoffMax.Duration == 0 {
+		o.PublishBackoffMax.Duration = time.Second
+	}
+
+	if o.ResultMode == "" {
+		o.ResultMode = resultModeBackend
+	}
+
+	if o.Serializer == "" {
+		o.Serializer = serializerJSON
+	}
+
+	if o.MaxResultMode == "" {
+		o.MaxResultMode = maxResultModeError
+	}
+
+	if o.BackendShape == "" {
+		o.BackendShape = backendShapeDict
 	}
 }
 
 func (o *options) validate() error {
-	if o.Timeout.Duration <= o.GetRetryInterval.Duration {
-		return fmt.Errorf("celery backend timeout duration cannot be shorter than check interval")
+	if o.GetRetryInterval.Duration <= 0 {
+		return fmt.Errorf("celery backend check interval (getinterval) must be positive")
+	}
+	// a shorter timeout would leave room for a single check
+	if o.Timeout.Duration < minTimeoutIntervals*o.GetRetryInterval.Duration {
+		return fmt.Errorf("celery backend timeout (%s) must be at least %d times the check interval (%s)",
+			o.Timeout.Duration, minTimeoutIntervals, o.GetRetryInterval.Duration)
+	}
+
+	if o.CheckTimeout.Duration < 0 || o.CheckTimeout.Duration > o.Timeout.Duration {
+		return fmt.Errorf("celery backend check timeout must be positive and cannot be longer than timeout")
+	}
+
+	if o.PollBackoff < 1 {
+		return fmt.Errorf("celery poll backoff factor cannot be lower than 1")
+	}
+
+	if !o.TLS && (o.CACertFile != "" || o.CertFile != "" || o.KeyFile != "" || o.InsecureSkipVerify) {
+		return fmt.Errorf("celery TLS options require tls to be enabled")
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return fmt.Errorf("celery TLS client certificate requires both certFile and keyFile")
+	}
+
+	switch o.Broker {
+	case brokerRedis:
+		if len(o.Queue.Bindings) > 0 {
+			return fmt.Errorf("celery queue bindings are only declared by the amqp broker")
+		}
+	case brokerAMQP:
+		if o.BrokerURL == "" {
+			return fmt.Errorf("celery amqp broker requires a brokerUrl")
+		}
+		if o.QueueType != queueTypeList || o.VerifyPublish || o.QueueTTL.Duration > 0 {
+			return fmt.Errorf("celery queueType, verifyPublish and queueTTL options are not supported by the amqp broker")
+		}
+	default:
+		return fmt.Errorf("celery broker %q is not supported", o.Broker)
+	}
+
+	if o.VerifyPublish && o.QueueType != queueTypeList {
+		return fmt.Errorf("celery verifyPublish requires a list queueType")
+	}
+
+	if o.QueueTTL.Duration < 0 {
+		return fmt.Errorf("celery queue TTL cannot be negative")
+	}
+
+	if o.PoolSize < 0 || o.PublishPoolSize < 0 || o.BackendPoolSize < 0 || o.MinIdleConns < 0 {
+		return fmt.Errorf("celery connection pool sizes cannot be negative")
+	}
+	if o.MaxRetries < -1 {
+		return fmt.Errorf("celery maxRetries cannot be lower than -1 (retries disabled)")
 	}
 
-	if o.Queue == "" {
+	if o.MaxWaitDuration.Duration < 0 {
+		return fmt.Errorf("celery max wait duration cannot be negative")
+	}
+
+	if o.Queue.Name == "" {
 		return fmt.Errorf("celery target queue cannot be empty")
 	}
 
@@ -179,16 +484,188 @@ func (o *options) validate() error {
 	if len(o.SentinelAddrs) >= 0 && o.MasterName == "" {
 		return fmt.Errorf("celery endpoint redis MasterName cannot be empty")
 	}
+	if len(o.SentinelAddrs) > 0 && len(o.ClusterAddrs) > 0 {
+		return fmt.Errorf("celery endpoint cannot be both a sentinel (addrs) and a cluster (clusterAddrs)")
+	}
+	if o.ResultKeyHashtag && len(o.ClusterAddrs) == 0 {
+		return fmt.Errorf("celery resultKeyHashtag only applies to a cluster (clusterAddrs)")
+	}
+
+	if o.ExpiresIn.Duration < 0 {
+		return fmt.Errorf("celery task expiresIn duration must be positive")
+	}
+
+	if o.PublishMaxRetries < 0 {
+		return fmt.Errorf("celery publish max retries cannot be negative")
+	}
+
+	if o.PublishBackoffMax.Duration < 0 {
+		return fmt.Errorf("celery publish backoff max duration must be positive")
+	}
+
+	if o.MaxMessages < 0 {
+		return fmt.Errorf("celery max messages cannot be negative")
+	}
+
+	if o.MaxResultBytes < 0 {
+		return fmt.Errorf("celery max result bytes cannot be negative")
+	}
+
+	switch o.MaxResultMode {
+	case maxResultModeError, maxResultModeTruncate:
+	default:
+		return fmt.Errorf("celery max result mode %q is not supported", o.MaxResultMode)
+	}
+
+	switch o.BackendShape {
+	case backendShapeDict, backendShapeBare:
+	default:
+		return fmt.Errorf("celery backend shape %q is not supported", o.BackendShape)
+	}
+
+	for _, exchangeType := range []string{o.ExchangeType, o.Queue.ExchangeType} {
+		switch exchangeType {
+		case "", "direct", "topic", "fanout", "headers":
+		default:
+			return fmt.Errorf("celery exchange type %q is not supported", exchangeType)
+		}
+	}
+
+	switch o.QueueType {
+	case queueTypeList, queueTypeSet:
+	default:
+		return fmt.Errorf("celery queue type %q is not supported", o.QueueType)
+	}
+
+	err := validateSerializer(o.Serializer)
+	if err != nil {
+		return err
+	}
+
+	switch o.ResultMode {
+	case resultModeBackend:
+	case resultModeCustom:
+		if o.ResultFn == nil {
+			return fmt.Errorf("celery custom result mode requires a resultFn callback")
+		}
+		if o.RequireBackend {
+			return fmt.Errorf("celery requireBackend can only check the redis result backend")
+		}
+	default:
+		return fmt.Errorf("celery result mode %q is not supported", o.ResultMode)
+	}
+
+	return nil
+}
+
+// redacted returns a copy of the options whose credentials are hidden, to be logged.
+func (o options) redacted() options {
+	if o.Password != "" {
+		o.Password = "***"
+	}
+	o.Url = redactURL(o.Url)
+	o.BrokerURL = redactURL(o.BrokerURL)
+	return o
+}
+
+// String formats the options like %+v does, to be logged: credentials are hidden, optional
+// (pointer) values are printed instead of their address and unexported fields are left out.
+func (o options) String() string {
+	value := reflect.ValueOf(o.redacted())
+	fields := make([]string, 0, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if fieldValue.Kind() == reflect.Pointer && !fieldValue.IsNil() {
+			fieldValue = fieldValue.Elem()
+		}
+		fields = append(fields, fmt.Sprintf("%s:%+v", field.Name, fieldValue.Interface()))
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+// redactURL hides the password of a URL userinfo.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// can't tell the credentials apart
+		return "***"
+	}
+	return u.Redacted()
+}
+
+// defaultProducerID identifies the k6 process as <hostname>:<pid>.
+func defaultProducerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// validateSerializer checks a task body serializer is supported.
+func validateSerializer(serializer string) error {
+	switch serializer {
+	case serializerJSON, serializerProtobuf, serializerMsgpack, serializerYAML:
+		return nil
+	}
+	if _, ok := registeredSerializer(serializer); ok {
+		return nil
+	}
+	return fmt.Errorf("celery serializer %q is not supported", serializer)
+}
+
+// queueOption is the target queue, given either as a bare queue name
+// or as an object with explicit exchange bindings.
+type queueOption struct {
+	Name         string `json:"name"`
+	Exchange     string `json:"exchange,omitempty"`
+	ExchangeType string `json:"exchangeType,omitempty"`
+	RoutingKey   string `json:"routingKey,omitempty"`
+	// Bindings are routing keys the amqp broker binds the queue to the exchange with,
+	// the Redis transport has no exchanges to bind.
+	Bindings []string `json:"bindings,omitempty"`
+}
+
+func (q *queueOption) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*q = queueOption{Name: name}
+		return nil
+	}
 
+	// alias type avoids recursing into this method
+	type queueObject queueOption
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	var object queueObject
+	if err := decoder.Decode(&object); err != nil {
+		return fmt.Errorf("queue must be a name or a {name, exchange, exchangeType, routingKey, bindings} object %w", err)
+	}
+	*q = queueOption(object)
 	return nil
 }
 
 // newOptionsFrom validates and instantiates an options struct from its map representation
 // as obtained by calling a Goja's Runtime.ExportTo.
 func newOptionsFrom(argument map[string]interface{}) (*options, error) {
+	var opts options
+	err := decodeOptions(argument, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &opts, nil
+}
+
+// decodeOptions decodes an options map representation into target through JSON.
+func decodeOptions(argument map[string]interface{}, target interface{}) error {
 	jsonStr, err := json.Marshal(argument)
 	if err != nil {
-		return nil, fmt.Errorf("unable to serialize options to JSON %w", err)
+		return fmt.Errorf("unable to serialize options to JSON %w", err)
 	}
 
 	// Instantiate a JSON decoder which will error on unknown
@@ -197,59 +674,1108 @@ func newOptionsFrom(argument map[string]interface{}) (*options, error) {
 	decoder := json.NewDecoder(bytes.NewReader(jsonStr))
 	decoder.DisallowUnknownFields()
 
-	var opts options
-	err = decoder.Decode(&opts)
+	err = decoder.Decode(target)
+	if err != nil {
+		return fmt.Errorf("unable to decode options %w", err)
+	}
+
+	return nil
+}
+
+// delayOptions are the per submission options given to delayWithOptions.
+type delayOptions struct {
+	Args    []interface{}          `json:"args,omitempty"`
+	Kwargs  map[string]interface{} `json:"kwargs,omitempty"`
+	Group   string                 `json:"group,omitempty"`
+	Headers map[string]interface{} `json:"headers,omitempty"`
+	// Countdown defers the task execution by this many seconds after submission.
+	Countdown float64 `json:"countdown,omitempty"`
+	// ETA defers the task execution until this RFC3339 timestamp.
+	ETA string `json:"eta,omitempty"`
+	// Retries simulates a task already retried this many times.
+	Retries int `json:"retries,omitempty"`
+	// Serializer overrides the client serializer for this submission.
+	Serializer string `json:"serializer,omitempty"`
+
+	eta time.Time
+}
+
+// newDelayOptionsFrom instantiates delayOptions from the JS options object.
+func newDelayOptionsFrom(rt *goja.Runtime, value goja.Value) (*delayOptions, error) {
+	var opts delayOptions
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return &opts, nil
+	}
+
+	var optionsArg map[string]interface{}
+	err := rt.ExportTo(value, &optionsArg)
+	if err != nil {
+		return nil, errors.New("unable to parse delay options object")
+	}
+
+	err = decodeOptions(optionsArg, &opts)
 	if err != nil {
-		return nil, fmt.Errorf("unable to decode options %w", err)
+		return nil, err
+	}
+
+	if opts.Countdown < 0 {
+		return nil, errors.New("countdown cannot be negative")
+	}
+	if opts.Retries < 0 {
+		return nil, errors.New("retries cannot be negative")
+	}
+	if opts.Serializer != "" {
+		err = validateSerializer(opts.Serializer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.ETA != "" {
+		if opts.Countdown > 0 {
+			return nil, errors.New("countdown and eta cannot be both set")
+		}
+		opts.eta, err = time.Parse(time.RFC3339, opts.ETA)
+		if err != nil {
+			return nil, fmt.Errorf("invalid eta; reason: %w", err)
+		}
 	}
 
 	return &opts, nil
 }
 
+// taskOptions converts delay options to the celery client submission settings.
+func (o *delayOptions) taskOptions() TaskOptions {
+	taskOpts := TaskOptions{Headers: o.Headers, Kwargs: o.Kwargs, Retries: o.Retries, Serializer: o.Serializer}
+	if o.Group != "" {
+		taskOpts.Headers = mergeHeaders(o.Headers, map[string]interface{}{"group": o.Group})
+	}
+	if o.Countdown > 0 {
+		// countdown is relative to the submission
+		eta := time.Now().UTC().Add(time.Duration(o.Countdown * float64(time.Second)))
+		taskOpts.ETA = &eta
+	} else if !o.eta.IsZero() {
+		taskOpts.ETA = &o.eta
+	}
+
+	return taskOpts
+}
+
 // Submits a new task to celery broker
-// It only supports args (no kwargs)
-func (c *Celery) Delay(taskName string, args ...interface{}) (string, error) {
-	ctx := context.Background()
-	taskId, err := c.client.Delay(ctx, taskName, c.queue, args)
+// It only supports args, see DelayKwargs for keyword arguments
+// It fails once the client has published maxMessages tasks (if set).
+func (c *Celery) Delay(taskName string, jsArgs ...goja.Value) (string, error) {
+	taskName, err := normalizeTaskName(taskName)
+	if err != nil {
+		return "", err
+	}
+	args := exportArgs(jsArgs)
+	queue, err := c.route(taskName, args)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.checkPublishLimit(1)
 	if err != nil {
 		return "", err
 	}
+
+	ctx := c.vu.Context()
+	taskId, err := c.client.Delay(ctx, queue, taskName, args...)
+	if err != nil {
+		return "", err
+	}
+	c.recordSubmit(taskId, queue)
 	return taskId, nil
 }
 
-// Check if task result is filled or still empty
-// It's a sync call with instant result.
-func (c *Celery) TaskCompleted(taskID string) (bool, error) {
-	ctx := context.Background()
-	result, err := c.client.GetResult(ctx, taskID)
+// TaskHandle is a submitted task, whose result can be checked later on.
+type TaskHandle struct {
+	ID string `js:"id"`
+
+	celery *Celery
+}
+
+// Submits a new task like delay, returning a handle instead of the task ID.
+func (c *Celery) DelayHandle(taskName string, jsArgs ...goja.Value) (*TaskHandle, error) {
+	taskName, err := normalizeTaskName(taskName)
 	if err != nil {
-		if err.Error() == "result not available" { // error message is hardcoded in client lib
-			return false, nil
-		}
-		return false, err
+		return nil, err
+	}
+	args := exportArgs(jsArgs)
+	queue, err := c.route(taskName, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.checkPublishLimit(1)
+	if err != nil {
+		return nil, err
 	}
 
-	return (result != nil), nil
+	taskId, err := c.client.ApplyAsync(c.vu.Context(), queue, taskName, args, TaskOptions{})
+	if err != nil {
+		return nil, err
+	}
+	c.recordSubmit(taskId, queue)
+	return &TaskHandle{ID: taskId, celery: c}, nil
 }
 
-// Wait for task to be completed until timeout is reached
-// It's a blocking call that do a periodic check for any task result
-// It returns true if task is processed, or false if timeout is reached.
-func (c *Celery) WaitForTaskCompleted(taskID string) (bool, error) {
-	ticker := time.NewTicker(c.getRetryInterval)
-	timeoutChan := time.After(c.timeout)
-	for {
-		select {
-		case <-timeoutChan:
-			return false, nil
-		case <-ticker.C:
-			completed, _ := c.TaskCompleted(taskID)
-			if completed != true {
-				continue
-			}
-			return true, nil
+// Wait for the task to be completed, then run fn against its result (same fields as getResult)
+// like a k6 check: the checks metric is emitted with the check name tag.
+// The check fails without calling fn if timeout is reached.
+// It returns whether the check passed.
+func (h *TaskHandle) Check(name string, fn goja.Callable) (bool, error) {
+	c := h.celery
+	passed := false
+	if result, _ := c.waitForResult(h.ID, c.timeout); result != nil {
+		value, err := fn(goja.Undefined(), c.vu.Runtime().ToValue(result.Document()))
+		if err != nil {
+			return false, err
+		}
+		passed = value.ToBoolean()
+	}
+
+	if state := c.vu.State(); state != nil {
+		sample := 0.0
+		if passed {
+			sample = 1
 		}
+		pushSample(c.vu, state.BuiltinMetrics.Checks, sample, map[string]string{"check": name})
+	}
+
+	return passed, nil
+}
+
+// Submits a new task to another queue than the client one
+// The message is routed with the queue name as exchange and routing key.
+// It fails once the client has published maxMessages tasks (if set).
+func (c *Celery) DelayTo(queue string, taskName string, jsArgs ...goja.Value) (string, error) {
+	if queue == "" {
+		return "", errors.New("celery target queue cannot be empty")
+	}
+	taskName, err := normalizeTaskName(taskName)
+	if err != nil {
+		return "", err
+	}
+	args := exportArgs(jsArgs)
+
+	err = c.checkPublishLimit(1)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := c.vu.Context()
+	taskId, err := c.client.ApplyAsync(ctx, queue, taskName, args, TaskOptions{})
+	if err != nil {
+		return "", err
 	}
+	c.recordSubmit(taskId, queue)
+	return taskId, nil
+}
+
+// Submits a copy of a task to each queue in a single broker round-trip, each copy having its own task ID
+// Messages are routed with the queue names as exchange and routing key, except for the client queue.
+// The fanout isn't atomic: a failure may leave copies in some of the queues only.
+// It returns the task IDs by queue.
+// It fails once the client has published maxMessages tasks (if set).
+func (c *Celery) DelayFanout(taskName string, args []interface{}, queues []string) (map[string]string, error) {
+	taskName, err := normalizeTaskName(taskName)
+	if err != nil {
+		return nil, err
+	}
+	if len(queues) == 0 {
+		return nil, errors.New("fanout requires at least one queue")
+	}
+	for _, queue := range queues {
+		if queue == "" {
+			return nil, errors.New("celery target queue cannot be empty")
+		}
+	}
+
+	err = c.checkPublishLimit(int64(len(queues)))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.timeout)
+	defer cancel()
+	taskIDs, err := c.client.ApplyAsyncFanout(ctx, queues, taskName, args, TaskOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for queue, taskID := range taskIDs {
+		c.recordSubmit(taskID, queue)
+	}
+
+	return taskIDs, nil
+}
+
+// Submits a new task to celery broker with positional and keyword arguments
+// It fails once the client has published maxMessages tasks (if set).
+func (c *Celery) DelayKwargs(taskName string, args []interface{}, kwargs map[string]interface{}) (string, error) {
+	taskName, err := normalizeTaskName(taskName)
+	if err != nil {
+		return "", err
+	}
+	queue, err := c.route(taskName, args)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.checkPublishLimit(1)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := c.vu.Context()
+	taskId, err := c.client.ApplyAsync(ctx, queue, taskName, args, TaskOptions{Kwargs: kwargs})
+	if err != nil {
+		return "", err
+	}
+	c.recordSubmit(taskId, queue)
+	return taskId, nil
+}
+
+// Submits a new task to celery broker with per submission options
+// (args, kwargs, headers, group header, countdown or eta, retries, serializer)
+// It fails once the client has published maxMessages tasks (if set).
+func (c *Celery) DelayWithOptions(taskName string, jsOpts goja.Value) (string, error) {
+	taskName, err := normalizeTaskName(taskName)
+	if err != nil {
+		return "", err
+	}
+	opts, err := newDelayOptionsFrom(c.vu.Runtime(), jsOpts)
+	if err != nil {
+		return "", fmt.Errorf("invalid delay options; reason: %w", err)
+	}
+	queue, err := c.route(taskName, opts.Args)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.checkPublishLimit(1)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := c.vu.Context()
+	taskId, err := c.client.ApplyAsync(ctx, queue, taskName, opts.Args, opts.taskOptions())
+	if err != nil {
+		return "", err
+	}
+	c.recordSubmit(taskId, queue)
+	return taskId, nil
+}
+
+// route returns the queue a task is submitted to: the one returned by routeFn,
+// or the client queue if routeFn isn't set or returns an empty value.
+func (c *Celery) route(taskName string, args []interface{}) (string, error) {
+	if c.routeFn == nil {
+		return c.queue, nil
+	}
+
+	rt := c.vu.Runtime()
+	value, err := c.routeFn(goja.Undefined(), rt.ToValue(taskName), rt.ToValue(args))
+	if err != nil {
+		return "", fmt.Errorf("route callback failed; reason: %w", err)
+	}
+	if goja.IsUndefined(value) || goja.IsNull(value) || value.String() == "" {
+		return c.queue, nil
+	}
+
+	return value.String(), nil
+}
+
+// routeBatch returns the queue a batch is submitted to in a single round-trip,
+// all of its tasks having to be routed to the same queue.
+func (c *Celery) routeBatch(taskName string, argsList [][]interface{}) (string, error) {
+	if c.routeFn == nil || len(argsList) == 0 {
+		return c.queue, nil
+	}
+
+	queue, err := c.route(taskName, argsList[0])
+	if err != nil {
+		return "", err
+	}
+	for i, args := range argsList[1:] {
+		taskQueue, err := c.route(taskName, args)
+		if err != nil {
+			return "", err
+		}
+		if taskQueue != queue {
+			return "", fmt.Errorf("tasks of a batch must be routed to a single queue, task 0 is routed to %s and task %d to %s",
+				queue, i+1, taskQueue)
+		}
+	}
+
+	return queue, nil
+}
+
+// DelayBatchResult is the outcome of DelayBatch.
+type DelayBatchResult struct {
+	// TaskIDs are the IDs of the published tasks.
+	TaskIDs []string `js:"taskIds"`
+	// Errors are the encoding errors of the tasks that couldn't be published.
+	Errors []string `js:"errors"`
+}
+
+// Submits one task per args of argsList in a single broker round-trip
+// Tasks failing to encode are skipped: the published task IDs are returned along with the encoding errors,
+// so they can still be awaited. It fails if the batch can't be published at all.
+// It fails once the client has published maxMessages tasks (if set).
+func (c *Celery) DelayBatch(taskName string, argsList [][]interface{}) (*DelayBatchResult, error) {
+	taskName, err := normalizeTaskName(taskName)
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := c.routeBatch(taskName, argsList)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.checkPublishLimit(int64(len(argsList)))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.timeout)
+	defer cancel()
+	taskIDs, encodeErrs, err := c.client.ApplyAsyncMany(ctx, queue, taskName, argsList, TaskOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, taskID := range taskIDs {
+		c.recordSubmit(taskID, queue)
+	}
+
+	batch := &DelayBatchResult{TaskIDs: taskIDs, Errors: make([]string, len(encodeErrs))}
+	for i, encodeErr := range encodeErrs {
+		batch.Errors[i] = encodeErr.Error()
+	}
+
+	return batch, nil
+}
+
+// Submits one task per args of argsList in a single broker round-trip, then waits for all of them
+// The client timeout covers both the submission and the wait.
+// Nothing is published if a task fails to encode, so no task is left without being awaited.
+// It returns the result document of each task by task ID (failed tasks have a FAILURE status),
+// {task_id, error} for results that can't be decoded, null for tasks not completed before timeout.
+// It fails once the client has published maxMessages tasks (if set).
+func (c *Celery) DelayBatchAndWait(taskName string, argsList [][]interface{}, jsOpts goja.Value) (map[string]interface{}, error) {
+	deadline := time.Now().Add(c.timeout)
+	taskName, err := normalizeTaskName(taskName)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := newDelayOptionsFrom(c.vu.Runtime(), jsOpts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delay options; reason: %w", err)
+	}
+	if opts.Args != nil {
+		return nil, errors.New("invalid delay options; reason: args are given by argsList")
+	}
+
+	queue, err := c.routeBatch(taskName, argsList)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.checkPublishLimit(int64(len(argsList)))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithDeadline(c.vu.Context(), deadline)
+	taskIDs, err := c.client.ApplyAsyncAll(ctx, queue, taskName, argsList, opts.taskOptions())
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	for _, taskID := range taskIDs {
+		c.recordSubmit(taskID, queue)
+	}
+
+	completed, undecodable, _ := c.waitForResults(taskIDs, time.Until(deadline), nil)
+	results := make(map[string]interface{}, len(taskIDs))
+	for _, taskID := range taskIDs {
+		results[taskID] = nil
+		if result, ok := completed[taskID]; ok {
+			results[taskID] = result.Document()
+		} else if decodeErr, ok := undecodable[taskID]; ok {
+			results[taskID] = map[string]interface{}{"task_id": taskID, "error": decodeErr.Error()}
+		}
+	}
+
+	return results, nil
+}
+
+// recordSubmit accounts for a published task and emits the celery_tasks_submitted
+// counter tagged with the queue it was routed to.
+func (c *Celery) recordSubmit(taskID string, queue string) {
+	c.published.Add(1)
+	c.submittedAt.Store(taskID, time.Now())
+	pushSample(c.vu, c.metrics.TasksSubmitted, 1, map[string]string{"queue": queue})
+}
+
+// checkPublishLimit fails if publishing n more tasks would exceed maxMessages.
+func (c *Celery) checkPublishLimit(n int64) error {
+	if c.maxMessages > 0 && c.published.Load()+n > c.maxMessages {
+		return fmt.Errorf("celery client reached its limit of %d published messages", c.maxMessages)
+	}
+
+	return nil
+}
+
+// Validate a big integer given as a numeric string, to be passed as a task arg without losing precision
+// The string is sent as is, the worker has to parse it.
+func (c *Celery) BigInt(value string) (string, error) {
+	if _, ok := new(big.Int).SetString(value, 10); !ok {
+		return "", fmt.Errorf("%q is not a base 10 integer", value)
+	}
+
+	return value, nil
+}
+
+// exportArgs converts JS call arguments to task args.
+// Trailing undefined arguments are dropped (`delay(name, undefined)` sends no args
+// like `delay(name)`), while null arguments are kept as JSON null.
+func exportArgs(jsArgs []goja.Value) []interface{} {
+	for len(jsArgs) > 0 && goja.IsUndefined(jsArgs[len(jsArgs)-1]) {
+		jsArgs = jsArgs[:len(jsArgs)-1]
+	}
+
+	args := make([]interface{}, len(jsArgs))
+	for i, arg := range jsArgs {
+		args[i] = arg.Export()
+	}
+
+	return args
+}
+
+// Check if task result is filled with a terminal state (SUCCESS, FAILURE or REVOKED)
+// or still empty/in progress
+// It's a sync call with instant result, bounded by checkTimeout.
+func (c *Celery) TaskCompleted(taskID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	result, err := c.client.GetResult(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return (result != nil && result.Ready()), nil
+}
+
+// Check if several tasks have been completed, like taskCompleted
+// It's a sync call fetching all results in a single backend round-trip,
+// tasks without result yet or whose result can't be decoded are not completed.
+func (c *Celery) TasksCompleted(taskIDs []string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	results, _, err := c.client.GetResults(ctx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	completed := make(map[string]bool, len(taskIDs))
+	for i, result := range results {
+		completed[taskIDs[i]] = result != nil && result.Ready()
+	}
+
+	return completed, nil
+}
+
+// Check if task has failed (its result status is FAILURE)
+// Tasks without result yet or in any other status (PENDING, RETRY, SUCCESS...) haven't failed.
+func (c *Celery) TaskFailed(taskID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	result, err := c.client.GetResult(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return result.Status == "FAILURE", nil
+}
+
+// Get the number of times a task was retried, as reported in its result.
+// It requires result_extended to be enabled on the worker side.
+func (c *Celery) GetRetries(taskID string) (int, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	result, err := c.client.GetResult(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, fmt.Errorf("task %s result not available", taskID)
+		}
+		return 0, err
+	}
+
+	return result.Retries, nil
+}
+
+// Get the IDs of the tasks spawned by a task (e.g. a chord header), flattened from its result children
+// It returns an empty list if the task result is not available.
+func (c *Celery) GetChildIds(taskID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	result, err := c.client.GetResult(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	return result.ChildIDs(), nil
+}
+
+// Wait for task to be completed then validate its result against the resultSchema option
+// It returns the list of validation errors, empty if the result conforms to the schema.
+func (c *Celery) ValidateResult(taskID string) ([]string, error) {
+	if c.resultSchema == nil {
+		return nil, errors.New("celery client has no resultSchema configured")
+	}
+
+	completed, err := c.WaitForTaskCompleted(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, fmt.Errorf("task %s result not available before timeout", taskID)
+	}
+
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	result, err := c.client.GetResult(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return validateAgainstSchema(c.resultSchema, result.Result)
+}
+
+// Get the full result of a task: {task_id, status, result, traceback, children, date_done, retries}
+// It returns null if the task result is not available yet.
+func (c *Celery) GetResult(taskID string) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	result, err := c.client.GetResult(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return result.Document(), nil
+}
+
+// Get the exception of a failed task, parsed from its result
+// It returns null if the task result is not available or if the task didn't fail.
+func (c *Celery) GetTraceback(taskID string) (*TaskException, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	result, err := c.client.GetResult(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return result.Exception(), nil
+}
+
+// Count tasks having a result in a terminal state (SUCCESS, FAILURE or REVOKED).
+// Results still in progress (STARTED, RETRY...) or that can't be decoded are not counted.
+// It's a sync call fetching all results in a single backend round-trip.
+func (c *Celery) CountCompleted(taskIDs []string) (int, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	results, _, err := c.client.GetResults(ctx, taskIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, result := range results {
+		if result != nil && result.Ready() {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Delete task results from the backend, e.g. to clean it up after a scenario
+// It returns the number of results removed.
+func (c *Celery) ClearResults(taskIDs []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.timeout)
+	defer cancel()
+	return c.client.DeleteResults(ctx, taskIDs)
+}
+
+// List the IDs of tasks having a result in the backend, e.g. to inspect or clean results up
+// The pattern (glob-style, "*" if empty) is matched against task IDs, the resultKeyPrefix being prepended.
+// Keys are enumerated with SCAN, so Redis is not blocked, but it still reads the whole keyspace.
+func (c *Celery) ListResultKeys(pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.timeout)
+	defer cancel()
+	return c.client.ListResults(ctx, pattern)
+}
+
+// Compute the throughput (tasks/sec) of completed tasks
+// The span goes from the earliest submission to the latest result date_done.
+// Submission times are only known for tasks published by this client, date_done
+// falls back to the submission time when missing from the result.
+// Results that can't be decoded are left out.
+func (c *Celery) Throughput(taskIDs []string) (float64, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	results, _, err := c.client.GetResults(ctx, taskIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	var start, end time.Time
+	completed := 0
+	for i, result := range results {
+		if result == nil || !result.Ready() {
+			continue
+		}
+
+		var submittedAt time.Time
+		if value, ok := c.submittedAt.Load(taskIDs[i]); ok {
+			submittedAt = value.(time.Time)
+		}
+		doneAt, err := result.DoneAt()
+		if err != nil {
+			doneAt = submittedAt
+		}
+		if doneAt.IsZero() {
+			continue
+		}
+		if submittedAt.IsZero() {
+			submittedAt = doneAt
+		}
+
+		completed++
+		if start.IsZero() || submittedAt.Before(start) {
+			start = submittedAt
+		}
+		if doneAt.After(end) {
+			end = doneAt
+		}
+	}
+
+	span := end.Sub(start)
+	if completed == 0 || span <= 0 {
+		return 0, nil
+	}
+
+	return float64(completed) / span.Seconds(), nil
+}
+
+// Wait for task to be completed until timeout is reached
+// It's a blocking call that do a periodic check for a task result in a terminal state,
+// results in progress (STARTED, RETRY...) are polled until they change.
+// An optional timeout in milliseconds overrides the client timeout for this call.
+// It returns true if task is processed, or false if timeout is reached.
+func (c *Celery) WaitForTaskCompleted(taskID string, timeoutMs ...int64) (bool, error) {
+	waitTimeout, err := c.callTimeout(timeoutMs)
+	if err != nil {
+		return false, err
+	}
+
+	result, _ := c.waitForResult(taskID, waitTimeout)
+	return result != nil, nil
+}
+
+// callTimeout returns the optional per call timeout in milliseconds, or the client timeout.
+func (c *Celery) callTimeout(timeoutMs []int64) (time.Duration, error) {
+	if len(timeoutMs) == 0 {
+		return c.timeout, nil
+	}
+
+	waitTimeout := time.Duration(timeoutMs[0]) * time.Millisecond
+	if waitTimeout <= 0 {
+		return 0, fmt.Errorf("timeout of %s must be positive", waitTimeout)
+	}
+	// a shorter timeout would leave room for a single check
+	if waitTimeout < minTimeoutIntervals*c.getRetryInterval {
+		return 0, fmt.Errorf("timeout of %s must be at least %d times the check interval (%s)",
+			waitTimeout, minTimeoutIntervals, c.getRetryInterval)
+	}
+
+	return waitTimeout, nil
+}
+
+// TimedCompletion is the outcome of WaitForTaskCompletedTimed.
+type TimedCompletion struct {
+	Completed bool `js:"completed"`
+	// DurationMs is the time from the first poll to the completed result being observed.
+	DurationMs float64 `js:"durationMs"`
+}
+
+// Wait for task to be completed like waitForTaskCompleted, also measuring how long it took
+// The duration goes from the first poll to the completed result being observed,
+// it's the time waited until timeout if the task didn't complete.
+func (c *Celery) WaitForTaskCompletedTimed(taskID string, timeoutMs ...int64) (*TimedCompletion, error) {
+	waitTimeout, err := c.callTimeout(timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+
+	result, elapsed := c.waitForResult(taskID, waitTimeout)
+	return &TimedCompletion{
+		Completed:  result != nil,
+		DurationMs: float64(elapsed) / float64(time.Millisecond),
+	}, nil
+}
+
+// Wait for task to be completed until timeout is reached, then return its full result
+// {task_id, status, result, traceback, children, date_done, retries}.
+// It returns null if timeout is reached.
+func (c *Celery) WaitForResult(taskID string) (map[string]interface{}, error) {
+	result, _ := c.waitForResult(taskID, c.timeout)
+	if result == nil {
+		return nil, nil
+	}
+
+	return result.Document(), nil
+}
+
+// Wait for task to be completed without blocking the VU, so it can do other calls meanwhile
+// It returns a promise resolved with the task result (same fields as getResult),
+// rejected if timeout is reached or if the wait is aborted with the VU context (e.g. the test is aborted).
+// It's not available with the custom result mode.
+func (c *Celery) WaitForResultAsync(taskID string) *goja.Promise {
+	promise, resolve, reject := c.vu.Runtime().NewPromise()
+	if c.customResults {
+		reject(errors.New("waitForResultAsync is not available with the custom resultMode"))
+		return promise
+	}
+
+	callback := c.vu.RegisterCallback()
+	go func() {
+		result, _ := c.waitForResult(taskID, c.timeout)
+		aborted := c.vu.Context().Err()
+		callback(func() error {
+			switch {
+			case result != nil:
+				resolve(result.Document())
+			case aborted != nil:
+				reject(fmt.Errorf("task %s result wait aborted; reason: %w", taskID, aborted))
+			default:
+				reject(fmt.Errorf("task %s result not available before timeout", taskID))
+			}
+			return nil
+		})
+	}()
+
+	return promise
+}
+
+// waitForResult polls a task result until it is in a terminal state.
+// It returns nil if timeout is reached, along with the time elapsed since the first poll.
+func (c *Celery) waitForResult(taskID string, timeout time.Duration) (*ResultMessage, time.Duration) {
+	waitStart := time.Now()
+	observed := false
+	var completed *ResultMessage
+	var firstPoll time.Time
+	c.poll(timeout, func() pollOutcome {
+		if firstPoll.IsZero() {
+			firstPoll = time.Now()
+		}
+		ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+		result, err := c.client.GetResult(ctx, taskID)
+		cancel()
+		if err != nil {
+			return pollPending
+		}
+		if !observed {
+			observed = true
+			c.recordTimeToResult(taskID, waitStart)
+		}
+		// the result document is rewritten on each state change (STARTED, RETRY...)
+		if !result.Ready() {
+			return pollPending
+		}
+		completed = result
+		return pollDone
+	})
+
+	if firstPoll.IsZero() {
+		// aborted before the first poll
+		return completed, 0
+	}
+	return completed, time.Since(firstPoll)
+}
+
+// pollOutcome is the outcome of a single poll check.
+type pollOutcome int
+
+const (
+	pollPending pollOutcome = iota
+	pollDone
+	pollAborted
+)
+
+// poll runs check every getinterval, growing by pollBackoff after each check, until
+// it is done or aborted or timeout is reached. The wait before a check never overshoots
+// the deadline, so the last check happens at the deadline.
+// timeout is capped to maxWaitDuration (if set).
+// It returns false as soon as the VU context is done (e.g. the test is aborted).
+// It returns true if check is done.
+func (c *Celery) poll(timeout time.Duration, check func() pollOutcome) bool {
+	if c.maxWaitDuration > 0 {
+		timeout = min(timeout, c.maxWaitDuration)
+	}
+	deadline := time.Now().Add(timeout)
+	interval := c.getRetryInterval
+	timer := time.NewTimer(min(interval, timeout))
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.vu.Context().Done():
+			return false
+		case <-timer.C:
+		}
+		switch check() {
+		case pollDone:
+			return true
+		case pollAborted:
+			return false
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false
+		}
+		// grown up to the remaining time only, a large factor would overflow the duration
+		interval = time.Duration(min(float64(interval)*c.pollBackoff, float64(remaining)))
+		timer.Reset(interval)
+	}
+}
+
+// recordTimeToResult emits the celery_time_to_result trend, measured from the task
+// submission, or from waitStart for tasks not published by this client.
+func (c *Celery) recordTimeToResult(taskID string, waitStart time.Time) {
+	start := waitStart
+	if submittedAt, ok := c.submittedAt.Load(taskID); ok {
+		start = submittedAt.(time.Time)
+	}
+	pushSample(c.vu, c.metrics.TimeToResult, metrics.D(time.Since(start)), nil)
+}
+
+// Wait for task to reach a given status (e.g. STARTED) until timeout is reached
+// It's a blocking call that do a periodic check of the task result status.
+// An optional timeout in milliseconds overrides the client timeout for this call.
+// It returns true if the status is reached, or false if timeout is reached or if the task
+// ended in another terminal status (as it can't reach the expected one anymore).
+func (c *Celery) WaitForStatus(taskID string, status string, timeoutMs ...int64) (bool, error) {
+	waitTimeout, err := c.callTimeout(timeoutMs)
+	if err != nil {
+		return false, err
+	}
+
+	reached := c.poll(waitTimeout, func() pollOutcome {
+		ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+		result, err := c.client.GetResult(ctx, taskID)
+		cancel()
+		if err != nil {
+			return pollPending
+		}
+		if result.Status == status {
+			return pollDone
+		}
+		if result.Ready() {
+			return pollAborted
+		}
+		return pollPending
+	})
+
+	return reached, nil
+}
+
+// WaitForNResult is the outcome of WaitForN.
+type WaitForNResult struct {
+	// Reached is true if at least n tasks completed before timeout.
+	Reached bool `js:"reached"`
+	// Completed lists the completed task IDs.
+	Completed []string `js:"completed"`
+}
+
+// Wait for at least n tasks out of taskIDs to be completed until timeout is reached
+// It's a blocking call polling all pending results with a single batched read per check.
+// An optional timeout in milliseconds overrides the client timeout for this call.
+// It returns whether n tasks completed, along with the completed task IDs (in the given order).
+// Results that can't be decoded are not polled again, their tasks don't count as completed.
+func (c *Celery) WaitForN(taskIDs []string, n int, timeoutMs ...int64) (*WaitForNResult, error) {
+	waitTimeout, err := c.callTimeout(timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	if n > len(taskIDs) {
+		return nil, fmt.Errorf("cannot wait for %d tasks out of %d", n, len(taskIDs))
+	}
+
+	outcome := &WaitForNResult{Completed: []string{}}
+	if n <= 0 {
+		outcome.Reached = true
+		return outcome, nil
+	}
+
+	results, _, _ := c.waitForResults(taskIDs, waitTimeout, func(completed int) bool { return completed >= n })
+	for _, taskID := range taskIDs {
+		if _, ok := results[taskID]; ok {
+			outcome.Completed = append(outcome.Completed, taskID)
+		}
+	}
+	outcome.Reached = len(outcome.Completed) >= n
+
+	return outcome, nil
+}
+
+// WaitForAllResult is the outcome of WaitForAll, task IDs being listed in the given order.
+type WaitForAllResult struct {
+	// Completed lists the tasks in SUCCESS state.
+	Completed []string `js:"completed"`
+	// Pending lists the tasks not completed when timeout was reached.
+	Pending []string `js:"pending"`
+	// Failed lists the tasks in FAILURE state or whose result can't be decoded.
+	Failed []string `js:"failed"`
+	// Revoked lists the tasks in REVOKED state.
+	Revoked []string `js:"revoked"`
+	// Errors are the decode errors of undecodable results by task ID.
+	Errors map[string]string `js:"errors"`
+}
+
+// Wait for all tasks to be completed until timeout is reached
+// It's a blocking call polling all pending results with a single batched read per check
+// It returns the completed, pending, failed and revoked task IDs, all tasks are processed if none is pending.
+// Results that can't be decoded are not polled again, their tasks are failed.
+func (c *Celery) WaitForAll(taskIDs []string) (*WaitForAllResult, error) {
+	results, decodeErrs, _ := c.waitForResults(taskIDs, c.timeout, nil)
+
+	outcome := &WaitForAllResult{
+		Completed: []string{},
+		Pending:   []string{},
+		Failed:    []string{},
+		Revoked:   []string{},
+		Errors:    map[string]string{},
+	}
+	for _, taskID := range taskIDs {
+		result, ok := results[taskID]
+		decodeErr, undecodable := decodeErrs[taskID]
+		switch {
+		case undecodable:
+			outcome.Failed = append(outcome.Failed, taskID)
+			outcome.Errors[taskID] = decodeErr.Error()
+		case !ok:
+			outcome.Pending = append(outcome.Pending, taskID)
+		case result.Status == "FAILURE":
+			outcome.Failed = append(outcome.Failed, taskID)
+		case result.Status == "REVOKED":
+			outcome.Revoked = append(outcome.Revoked, taskID)
+		default:
+			outcome.Completed = append(outcome.Completed, taskID)
+		}
+	}
+
+	return outcome, nil
+}
+
+// waitForResults polls pending results with a single batched read per check,
+// until they are all in a terminal state, done returns true for the number of completed results
+// (if set) or timeout is reached.
+// A result failing to decode is done as it won't be readable later on.
+// It returns the completed results and the decode errors by task ID, and the task IDs still pending.
+func (c *Celery) waitForResults(taskIDs []string, timeout time.Duration, done func(completed int) bool) (map[string]*ResultMessage, map[string]error, []string) {
+	completed := make(map[string]*ResultMessage, len(taskIDs))
+	undecodable := make(map[string]error)
+	pending := append([]string(nil), taskIDs...)
+	if len(pending) == 0 {
+		return completed, undecodable, pending
+	}
+
+	c.poll(timeout, func() pollOutcome {
+		ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+		results, decodeErrs, err := c.client.GetResults(ctx, pending)
+		cancel()
+		if err != nil {
+			return pollPending
+		}
+		stillPending := pending[:0]
+		for i, result := range results {
+			switch {
+			case decodeErrs[i] != nil:
+				undecodable[pending[i]] = decodeErrs[i]
+			case result == nil || !result.Ready():
+				stillPending = append(stillPending, pending[i])
+			default:
+				completed[pending[i]] = result
+			}
+		}
+		pending = stillPending
+		if len(pending) > 0 && (done == nil || !done(len(completed))) {
+			return pollPending
+		}
+		return pollDone
+	})
+
+	return completed, undecodable, pending
+}
+
+// Close the broker and backend connections
+// The client can't be used afterwards, closing it again is a no-op returning the first close error.
+func (c *Celery) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.client.Close()
+	})
+	return c.closeErr
+}
+
+// Get the last error returned by a Redis command: {message, time}
+// It returns null if the last command succeeded.
+func (c *Celery) LastError() *LastError {
+	return c.lastError.last.Load()
+}
+
+// Get the number of messages waiting in the client queue (priority queues included)
+// It's a sync call with instant result.
+func (c *Celery) QueueLength() (int64, error) {
+	ctx, cancel := context.WithTimeout(c.vu.Context(), c.checkTimeout)
+	defer cancel()
+	return c.client.QueueLength(ctx, c.queue)
+}
+
+// Wait for the client queue length to drop below threshold until timeout is reached
+// It's a blocking call that do a periodic check of the queue length
+// It returns true if the queue drained below threshold, or false if timeout is reached.
+func (c *Celery) WaitForQueueBelow(threshold int64) (bool, error) {
+	drained := c.poll(c.timeout, func() pollOutcome {
+		length, err := c.QueueLength()
+		if err != nil || length >= threshold {
+			return pollPending
+		}
+		return pollDone
+	})
+
+	return drained, nil
 }
 
 // Exports implements the modules.Instance interface and returns the exports
@@ -1,81 +1,513 @@
 package celery
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.k6.io/k6/js/modules"
+	"gopkg.in/yaml.v3"
 )
 
 type BrokerBackend interface {
 	Publish(ctx context.Context, message []byte, rawMessage string, queue string) error
+	PublishMany(ctx context.Context, messages [][]byte, queue string) error
+	PublishFanout(ctx context.Context, messages map[string][]byte) error
+	Peek(ctx context.Context, queue string) ([]byte, error)
 	Get(ctx context.Context, taskID string) *redis.StringCmd
+	GetMany(ctx context.Context, taskIDs []string) *redis.SliceCmd
+	QueueLength(ctx context.Context, queue string) (int64, error)
+	Delete(ctx context.Context, taskIDs []string) (int64, error)
+	ListResults(ctx context.Context, pattern string) ([]string, error)
+	Close() error
 }
 
 type ICeleryClient interface {
 	Delay(ctx context.Context, queue string, taskName string, args ...interface{}) (string, error)
+	ApplyAsync(ctx context.Context, queue string, taskName string, args []interface{}, taskOpts TaskOptions) (string, error)
+	ApplyAsyncMany(ctx context.Context, queue string, taskName string, argsList [][]interface{}, taskOpts TaskOptions) ([]string, []error, error)
+	ApplyAsyncAll(ctx context.Context, queue string, taskName string, argsList [][]interface{}, taskOpts TaskOptions) ([]string, error)
+	ApplyAsyncFanout(ctx context.Context, queues []string, taskName string, args []interface{}, taskOpts TaskOptions) (map[string]string, error)
 	GetResult(ctx context.Context, taskID string) (*ResultMessage, error)
+	GetResults(ctx context.Context, taskIDs []string) ([]*ResultMessage, []error, error)
+	QueueLength(ctx context.Context, queue string) (int64, error)
+	DeleteResults(ctx context.Context, taskIDs []string) (int64, error)
+	ListResults(ctx context.Context, pattern string) ([]string, error)
+	Close() error
 }
 
 type CeleryClient struct {
 	brokerBackend BrokerBackend
+	expiresIn     time.Duration
+	serializer    string
+	route         queueRoute
+	// defaultHeaders are added to every message, per submission headers take precedence
+	defaultHeaders map[string]interface{}
+	// producerHeaders identify the k6 instance publishing messages
+	producerHeaders map[string]interface{}
+	// vuHeaders returns the headers identifying the VU and iteration publishing a message (if enabled)
+	vuHeaders func() map[string]interface{}
+
+	correlationEqualsTaskID bool
+	minimalProperties       bool
+
+	// deliveryTagPrefix identifies the client in delivery tags, numbered with deliveryTags
+	// to keep them unique across VUs without generating a UUID per message
+	deliveryTagPrefix string
+	deliveryTags      atomic.Uint64
+
+	// sequenceHeader names the header carrying a per client message sequence number
+	sequenceHeader string
+	sequence       atomic.Int64
+
+	maxResultBytes int
+	maxResultMode  string
+	// bareResults is set when the backend stores the bare result value instead of the result document
+	bareResults bool
+
+	publishMaxRetries int
+	publishBackoffMax time.Duration
+
+	// verifyPublish reads back each published message from the queue head
+	verifyPublish bool
+	// bigIntArgsAsString rejects numbers beyond the JS safe integer range in task args,
+	// large integers having to be given as numeric strings
+	bigIntArgsAsString bool
 }
 
 // GetResult queries redis backend to get asynchronous result
 func (cc *CeleryClient) GetResult(ctx context.Context, taskID string) (*ResultMessage, error) {
 	val, err := cc.brokerBackend.Get(ctx, taskID).Bytes()
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "WRONGTYPE") {
+			// e.g. a hash or a list written by something else under the result key
+			return nil, fmt.Errorf("task %s result key holds a non-string value, celery results are stored as strings "+
+				"(check resultKeyPrefix and what writes this key); reason: %w", taskID, err)
+		}
+		return nil, err
+	}
+	return cc.decodeResult(taskID, val)
+}
+
+// GetResults queries redis backend to get several asynchronous results in a single round-trip.
+// Results not available yet are returned as nil.
+// A result failing to decode (malformed, oversized...) doesn't fail the others: it's returned
+// as nil along with its decode error, errors being indexed like results.
+func (cc *CeleryClient) GetResults(ctx context.Context, taskIDs []string) ([]*ResultMessage, []error, error) {
+	// MGET requires at least one key
+	if len(taskIDs) == 0 {
+		return nil, nil, nil
+	}
+
+	values, err := cc.brokerBackend.GetMany(ctx, taskIDs).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]*ResultMessage, len(values))
+	decodeErrs := make([]error, len(values))
+	for i, value := range values {
+		document, ok := value.(string)
+		if !ok {
+			continue
+		}
+		results[i], decodeErrs[i] = cc.decodeResult(taskIDs[i], []byte(document))
+	}
+
+	return results, decodeErrs, nil
+}
+
+// QueueLength returns the number of messages waiting in a queue.
+func (cc *CeleryClient) QueueLength(ctx context.Context, queue string) (int64, error) {
+	return cc.brokerBackend.QueueLength(ctx, queue)
+}
+
+// DeleteResults removes task results from the backend and returns the number removed.
+func (cc *CeleryClient) DeleteResults(ctx context.Context, taskIDs []string) (int64, error) {
+	return cc.brokerBackend.Delete(ctx, taskIDs)
+}
+
+// ListResults returns the IDs of tasks having a result matching the pattern.
+func (cc *CeleryClient) ListResults(ctx context.Context, pattern string) ([]string, error) {
+	return cc.brokerBackend.ListResults(ctx, pattern)
+}
+
+func (cc *CeleryClient) Close() error {
+	return cc.brokerBackend.Close()
+}
+
+func (cc *CeleryClient) decodeResult(taskID string, val []byte) (*ResultMessage, error) {
+	val, compressed, err := decompressResult(val, cc.maxResultBytes)
 	if err != nil {
 		return nil, err
 	}
+
+	oversized := cc.maxResultBytes > 0 && len(val) > cc.maxResultBytes
+	if oversized && compressed {
+		// inflating stopped at the limit, the document can't be parsed to be truncated
+		return nil, fmt.Errorf("compressed result document exceeds maxResultBytes (%d) once inflated", cc.maxResultBytes)
+	}
+	if oversized && cc.maxResultMode != maxResultModeTruncate {
+		return nil, fmt.Errorf("result document of %d bytes exceeds maxResultBytes (%d)", len(val), cc.maxResultBytes)
+	}
+
 	var resultMessage ResultMessage
-	err = json.Unmarshal(val, &resultMessage)
+	switch {
+	case cc.bareResults && oversized:
+		// the whole document is the payload, it's only checked to be valid
+		resultMessage = ResultMessage{ID: taskID, Status: "SUCCESS"}
+		if !json.Valid(val) {
+			err = errors.New("result document is not valid JSON")
+		}
+	case cc.bareResults:
+		// a stored value means the task succeeded
+		resultMessage = ResultMessage{ID: taskID, Status: "SUCCESS"}
+		err = json.Unmarshal(val, &resultMessage.Result)
+	case oversized:
+		// the payload is skipped by the decoder rather than decoded then dropped
+		err = json.Unmarshal(val, &truncatedResultMessage{ResultMessage: &resultMessage})
+	default:
+		err = json.Unmarshal(val, &resultMessage)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if oversized {
+		// keep the metadata but don't hand over the payload to JS
+		resultMessage.Truncated = true
+	}
+
 	return &resultMessage, nil
 }
 
 func (cc *CeleryClient) Delay(ctx context.Context, queue string, taskName string, args ...interface{}) (messageId string, err error) {
+	return cc.ApplyAsync(ctx, queue, taskName, args, TaskOptions{})
+}
+
+// TaskOptions are the per submission settings of ApplyAsync.
+type TaskOptions struct {
+	// Headers are added to the message headers.
+	Headers map[string]interface{}
+	// Kwargs are the task keyword arguments.
+	Kwargs map[string]interface{}
+	// ETA defers the task execution until then (if set).
+	ETA *time.Time
+	// Retries is the number of times the task is reported as already retried.
+	Retries int
+	// Serializer overrides the client serializer (if set).
+	Serializer string
+}
+
+// ApplyAsync publishes a task like Delay, with per submission options.
+func (cc *CeleryClient) ApplyAsync(ctx context.Context, queue string, taskName string, args []interface{}, taskOpts TaskOptions) (string, error) {
+	messageId, message, err := cc.newMessage(queue, taskName, args, taskOpts)
+	if err != nil {
+		return "", err
+	}
+
+	err = cc.withRetries(ctx, func() error {
+		return cc.brokerBackend.Publish(ctx, message, string(message), queue)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if cc.verifyPublish {
+		err = cc.verifyPublished(ctx, queue, messageId, taskName)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return messageId, nil
+}
+
+// verifyPublished peeks the queue head and checks it decodes to the task just published.
+func (cc *CeleryClient) verifyPublished(ctx context.Context, queue string, messageId string, taskName string) error {
+	raw, err := cc.brokerBackend.Peek(ctx, queue)
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("published task %s not found at the head of queue %s", messageId, queue)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read back published task %s; reason: %w", messageId, err)
+	}
+
+	tm, err := decodeTaskMessage(raw)
+	if err != nil {
+		return fmt.Errorf("published task %s can't be decoded; reason: %w", messageId, err)
+	}
+	if tm != nil && (tm.ID != messageId || tm.Task != taskName) {
+		return fmt.Errorf("queue %s head is task %s (%s), expected published task %s (%s)", queue, tm.ID, tm.Task, messageId, taskName)
+	}
+
+	return nil
+}
+
+// ApplyAsyncMany publishes one task per args of argsList in a single broker round-trip.
+// Messages failing to encode are skipped: the IDs of the published tasks are returned
+// along with the encoding errors.
+func (cc *CeleryClient) ApplyAsyncMany(ctx context.Context, queue string, taskName string, argsList [][]interface{}, taskOpts TaskOptions) ([]string, []error, error) {
+	messageIds, messages, encodeErrs := cc.newMessages(queue, taskName, argsList, taskOpts)
+	err := cc.publishMany(ctx, queue, messages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return messageIds, encodeErrs, nil
+}
+
+// ApplyAsyncAll publishes one task per args of argsList in a single broker round-trip,
+// only if all of them encode: nothing is published otherwise.
+func (cc *CeleryClient) ApplyAsyncAll(ctx context.Context, queue string, taskName string, argsList [][]interface{}, taskOpts TaskOptions) ([]string, error) {
+	messageIds, messages, encodeErrs := cc.newMessages(queue, taskName, argsList, taskOpts)
+	if len(encodeErrs) > 0 {
+		return nil, errors.Join(encodeErrs...)
+	}
+	err := cc.publishMany(ctx, queue, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return messageIds, nil
+}
+
+// newMessages encodes one message per args of argsList, returning the encoded messages
+// and their IDs along with the errors of the messages failing to encode.
+func (cc *CeleryClient) newMessages(queue string, taskName string, argsList [][]interface{}, taskOpts TaskOptions) ([]string, [][]byte, []error) {
+	messageIds := make([]string, 0, len(argsList))
+	messages := make([][]byte, 0, len(argsList))
+	var encodeErrs []error
+	for i, args := range argsList {
+		messageId, message, err := cc.newMessage(queue, taskName, args, taskOpts)
+		if err != nil {
+			encodeErrs = append(encodeErrs, fmt.Errorf("task %d: %w", i, err))
+			continue
+		}
+		messageIds = append(messageIds, messageId)
+		messages = append(messages, message)
+	}
+
+	return messageIds, messages, encodeErrs
+}
+
+// publishMany publishes messages to the queue in a single broker round-trip, with retries.
+func (cc *CeleryClient) publishMany(ctx context.Context, queue string, messages [][]byte) error {
+	return cc.withRetries(ctx, func() error {
+		return cc.brokerBackend.PublishMany(ctx, messages, queue)
+	})
+}
+
+// ApplyAsyncFanout publishes a copy of a task to each queue in a single broker round-trip,
+// each copy having its own task ID. It returns the task IDs by queue.
+// The fanout isn't atomic, a retry after a partial failure publishes again to the queues already reached.
+func (cc *CeleryClient) ApplyAsyncFanout(ctx context.Context, queues []string, taskName string, args []interface{}, taskOpts TaskOptions) (map[string]string, error) {
+	messageIds := make(map[string]string, len(queues))
+	messages := make(map[string][]byte, len(queues))
+	for _, queue := range queues {
+		if _, ok := messages[queue]; ok {
+			return nil, fmt.Errorf("queue %s is listed more than once", queue)
+		}
+		messageId, message, err := cc.newMessage(queue, taskName, args, taskOpts)
+		if err != nil {
+			return nil, err
+		}
+		messageIds[queue] = messageId
+		messages[queue] = message
+	}
+
+	err := cc.withRetries(ctx, func() error {
+		return cc.brokerBackend.PublishFanout(ctx, messages)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messageIds, nil
+}
+
+// newMessage builds a task message, it returns the task ID and the encoded message.
+func (cc *CeleryClient) newMessage(queue string, taskName string, args []interface{}, taskOpts TaskOptions) (messageId string, encodedCeleryMessage []byte, err error) {
+	if cc.bigIntArgsAsString {
+		err = checkSafeIntegers(args)
+		if err == nil {
+			err = checkSafeIntegers(taskOpts.Kwargs)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
 	messageId = uuid.NewString()
+	var expires *string
+	if cc.expiresIn > 0 {
+		expiresAt := time.Now().UTC().Add(cc.expiresIn).Format(isoTimeFormat)
+		expires = &expiresAt
+	}
+	var eta *string
+	if taskOpts.ETA != nil {
+		etaAt := taskOpts.ETA.UTC().Format(isoTimeFormat)
+		eta = &etaAt
+	}
 	var encodedMessage string
-	encodedMessage, err = encodeMessage(taskName, messageId, args)
+	serializer := cc.serializer
+	if taskOpts.Serializer != "" {
+		serializer = taskOpts.Serializer
+	}
+	contentType, contentEncoding := "application/json", "utf-8"
+	switch serializer {
+	case serializerProtobuf:
+		// the task message can't be built without knowing the worker schema,
+		// so the body is given already encoded
+		contentType, contentEncoding = "application/x-protobuf", "binary"
+		if len(taskOpts.Kwargs) > 0 || eta != nil || taskOpts.Retries > 0 {
+			return "", nil, fmt.Errorf("protobuf serializer does not support kwargs, eta nor retries")
+		}
+		encodedMessage, err = encodeProtobufBody(args)
+	case serializerMsgpack:
+		contentType, contentEncoding = "application/x-msgpack", "binary"
+		encodedMessage, err = encodeMessage(msgpack.Marshal, taskName, messageId, args, taskOpts.Kwargs, eta, expires, taskOpts.Retries)
+	case serializerYAML:
+		contentType = "application/x-yaml"
+		encodedMessage, err = encodeMessage(yaml.Marshal, taskName, messageId, args, taskOpts.Kwargs, eta, expires, taskOpts.Retries)
+	case serializerJSON:
+		encodedMessage, err = encodeMessage(json.Marshal, taskName, messageId, args, taskOpts.Kwargs, eta, expires, taskOpts.Retries)
+	default:
+		// serializer names are validated with the options
+		registered, _ := registeredSerializer(serializer)
+		contentType, contentEncoding = registered.ContentType, registered.ContentEncoding
+		encodedMessage, err = encodeMessage(registered.Marshal, taskName, messageId, args, taskOpts.Kwargs, eta, expires, taskOpts.Retries)
+	}
 	if err != nil {
 		return
 	}
 
+	var sequenceHeaders map[string]interface{}
+	if cc.sequenceHeader != "" {
+		sequenceHeaders = map[string]interface{}{cc.sequenceHeader: cc.sequence.Add(1)}
+	}
+
+	var vuHeaders map[string]interface{}
+	if cc.vuHeaders != nil {
+		vuHeaders = cc.vuHeaders()
+	}
+
+	correlationID := uuid.NewString()
+	if cc.correlationEqualsTaskID {
+		// lets consumers keying results by correlation_id find them by task ID
+		correlationID = messageId
+	}
+
+	// reply_to is a random value we don't use,
+	// it can be skipped to save a UUID generation when consumers don't need it
+	var replyTo string
+	if !cc.minimalProperties {
+		replyTo = uuid.NewString()
+	}
+	deliveryTag := cc.deliveryTagPrefix + "-" + strconv.FormatUint(cc.deliveryTags.Add(1), 10)
+
 	celeryMessage := CeleryMessage{
 		Body:            encodedMessage,
-		ContentType:     "application/json",
-		ContentEncoding: "utf-8",
+		Headers:         mergeHeaders(cc.producerHeaders, vuHeaders, cc.defaultHeaders, taskOpts.Headers, sequenceHeaders),
+		ContentType:     contentType,
+		ContentEncoding: contentEncoding,
 		Properties: CeleryProperties{
 			BodyEncoding:  "base64",
-			CorrelationID: uuid.NewString(),
-			ReplyTo:       uuid.NewString(),
-			DeliveryInfo: CeleryDeliveryInfo{
-				Priority:   0,
-				RoutingKey: queue,
-				Exchange:   queue,
-			},
-			DeliveryMode: 2,
-			DeliveryTag:  uuid.NewString(),
+			CorrelationID: correlationID,
+			ReplyTo:       replyTo,
+			DeliveryInfo:  cc.deliveryInfo(queue),
+			DeliveryMode:  2,
+			DeliveryTag:   deliveryTag,
 		},
 	}
-	var encodedCeleryMessage []byte
 	encodedCeleryMessage, err = json.Marshal(celeryMessage)
 
-	if err != nil {
-		return
+	return
+}
+
+// publishBackoffMin is the backoff before the first publish retry.
+const publishBackoffMin = 10 * time.Millisecond
+
+// withRetries runs publish, retrying failures up to publishMaxRetries times
+// with a jittered exponential backoff capped to publishBackoffMax.
+// Retries stop as soon as ctx is done.
+func (cc *CeleryClient) withRetries(ctx context.Context, publish func() error) error {
+	backoff := publishBackoffMin
+	for attempt := 0; ; attempt++ {
+		err := publish()
+		if err == nil || attempt >= cc.publishMaxRetries {
+			return err
+		}
+
+		// full jitter
+		timer := time.NewTimer(rand.N(backoff) + 1)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("publish retries aborted; reason: %w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+
+		backoff = min(2*backoff, cc.publishBackoffMax)
 	}
+}
 
-	err = cc.brokerBackend.Publish(ctx, encodedCeleryMessage, string(encodedCeleryMessage), queue)
-	if err != nil {
-		return
+// mergeHeaders merges message headers, later ones taking precedence.
+func mergeHeaders(headers ...map[string]interface{}) map[string]interface{} {
+	var merged map[string]interface{}
+	for _, h := range headers {
+		for key, value := range h {
+			if merged == nil {
+				merged = make(map[string]interface{})
+			}
+			merged[key] = value
+		}
 	}
 
-	return
+	return merged
+}
+
+// queueRoute holds explicit exchange bindings configured for a queue.
+type queueRoute struct {
+	Queue        string
+	Exchange     string
+	ExchangeType string
+	RoutingKey   string
+}
+
+// deliveryInfo routes messages to the queue through its configured exchange
+// bindings, or through an exchange and routing key named after the queue.
+func (cc *CeleryClient) deliveryInfo(queue string) CeleryDeliveryInfo {
+	if queue == cc.route.Queue {
+		return CeleryDeliveryInfo{
+			Priority:     0,
+			RoutingKey:   cc.route.RoutingKey,
+			Exchange:     cc.route.Exchange,
+			ExchangeType: cc.route.ExchangeType,
+		}
+	}
+
+	return CeleryDeliveryInfo{
+		Priority:   0,
+		RoutingKey: queue,
+		Exchange:   queue,
+	}
 }
 
 type celery struct {
@@ -93,27 +525,32 @@ type CeleryMessage struct {
 type CeleryProperties struct {
 	BodyEncoding  string             `json:"body_encoding"`
 	CorrelationID string             `json:"correlation_id"`
-	ReplyTo       string             `json:"reply_to"`
+	ReplyTo       string             `json:"reply_to,omitempty"`
 	DeliveryInfo  CeleryDeliveryInfo `json:"delivery_info"`
 	DeliveryMode  int                `json:"delivery_mode"`
 	DeliveryTag   string             `json:"delivery_tag"`
 }
 
 type CeleryDeliveryInfo struct {
-	Priority   int    `json:"priority"`
-	RoutingKey string `json:"routing_key"`
-	Exchange   string `json:"exchange"`
+	Priority     int    `json:"priority"`
+	RoutingKey   string `json:"routing_key"`
+	Exchange     string `json:"exchange"`
+	ExchangeType string `json:"exchange_type,omitempty"`
 }
 
 type TaskMessage struct {
-	Task    string                 `json:"task"`
-	ID      string                 `json:"id"`
-	Args    []interface{}          `json:"args"`
-	Kwargs  map[string]interface{} `json:"kwargs"`
-	ETA     *string                `json:"eta"`
-	Retries int                    `json:"retries"`
+	Task    string                 `json:"task" msgpack:"task" yaml:"task"`
+	ID      string                 `json:"id" msgpack:"id" yaml:"id"`
+	Args    []interface{}          `json:"args" msgpack:"args" yaml:"args"`
+	Kwargs  map[string]interface{} `json:"kwargs" msgpack:"kwargs" yaml:"kwargs"`
+	ETA     *string                `json:"eta" msgpack:"eta" yaml:"eta"`
+	Expires *string                `json:"expires" msgpack:"expires" yaml:"expires"`
+	Retries int                    `json:"retries" msgpack:"retries" yaml:"retries"`
 }
 
+// isoTimeFormat matches python's datetime.isoformat used by celery for eta/expires.
+const isoTimeFormat = "2006-01-02T15:04:05.000000-07:00"
+
 // ResultMessage is return message received from broker
 type ResultMessage struct {
 	ID        string        `json:"task_id"`
@@ -121,23 +558,239 @@ type ResultMessage struct {
 	Traceback interface{}   `json:"traceback"`
 	Result    interface{}   `json:"result"`
 	Children  []interface{} `json:"children"`
+	DateDone  string        `json:"date_done"`
+	// Retries is only filled by workers with result_extended enabled.
+	Retries int `json:"retries"`
+	// Truncated is set when the result payload was dropped for exceeding maxResultBytes.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// truncatedResultMessage decodes the metadata of a result document, skipping its result payload.
+type truncatedResultMessage struct {
+	*ResultMessage
+	// shadows ResultMessage.Result
+	Result skippedValue `json:"result"`
+}
+
+// skippedValue is a JSON value that is validated but not decoded.
+type skippedValue struct{}
+
+func (skippedValue) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// Document returns the result fields keyed like the backend document, to be handed over to JS.
+func (rm *ResultMessage) Document() map[string]interface{} {
+	document := map[string]interface{}{
+		"task_id":   rm.ID,
+		"status":    rm.Status,
+		"result":    rm.Result,
+		"traceback": rm.Traceback,
+		"children":  rm.Children,
+		"date_done": rm.DateDone,
+		"retries":   rm.Retries,
+	}
+	if rm.Truncated {
+		document["truncated"] = true
+	}
+	if exception := rm.Exception(); exception != nil {
+		document["exception"] = map[string]interface{}{
+			"type":    exception.Type,
+			"message": exception.Message,
+			"module":  exception.Module,
+		}
+	}
+
+	return document
+}
+
+// dateDoneFormats are the layouts of date_done across celery versions
+// (naive UTC isoformat before 5.0, timezone aware after).
+var dateDoneFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999",
+}
+
+// DoneAt parses the result date_done.
+func (rm *ResultMessage) DoneAt() (time.Time, error) {
+	var err error
+	for _, layout := range dateDoneFormats {
+		var doneAt time.Time
+		doneAt, err = time.Parse(layout, rm.DateDone)
+		if err == nil {
+			return doneAt, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid result date_done %q %w", rm.DateDone, err)
+}
+
+// TaskException is the exception info of a FAILURE task.
+type TaskException struct {
+	Type      string      `js:"type"`
+	Message   string      `js:"message"`
+	Module    string      `js:"module"`
+	Traceback interface{} `js:"traceback"`
+}
+
+// ChildIDs flattens the result children to the list of child task IDs.
+// Children are serialized as result tuples: [[id, parent], null] for a task and
+// [[group_id, parent], [results...]] for a group, whose results are flattened.
+// Plain IDs and {"task_id": ...} objects are accepted too.
+func (rm *ResultMessage) ChildIDs() []string {
+	ids := []string{}
+	for _, child := range rm.Children {
+		ids = appendChildIDs(ids, child)
+	}
+	return ids
 }
 
-func encodeMessage(taskName string, messageId string, args []interface{}) (string, error) {
+func appendChildIDs(ids []string, child interface{}) []string {
+	switch c := child.(type) {
+	case string:
+		return append(ids, c)
+	case map[string]interface{}:
+		if id, ok := c["task_id"].(string); ok {
+			return append(ids, id)
+		}
+	case []interface{}:
+		if len(c) != 2 {
+			return ids
+		}
+		if results, ok := c[1].([]interface{}); ok {
+			// group: its own ID isn't a task
+			for _, result := range results {
+				ids = appendChildIDs(ids, result)
+			}
+			return ids
+		}
+		if node, ok := c[0].([]interface{}); ok && len(node) > 0 {
+			if id, ok := node[0].(string); ok {
+				return append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
 
+// Exception parses the exception celery stores as result of FAILURE tasks:
+// {"exc_type": ..., "exc_message": ..., "exc_module": ...}.
+// It returns nil when the task didn't fail.
+func (rm *ResultMessage) Exception() *TaskException {
+	if rm.Status != "FAILURE" {
+		return nil
+	}
+
+	exception := &TaskException{Traceback: rm.Traceback}
+	excInfo, ok := rm.Result.(map[string]interface{})
+	if !ok {
+		// non dict results come from custom exception serialization
+		exception.Message = fmt.Sprint(rm.Result)
+		return exception
+	}
+
+	exception.Type, _ = excInfo["exc_type"].(string)
+	exception.Module, _ = excInfo["exc_module"].(string)
+	switch excMessage := excInfo["exc_message"].(type) {
+	case string:
+		exception.Message = excMessage
+	case []interface{}:
+		// exception args, a single message most of the time
+		if len(excMessage) == 1 {
+			exception.Message = fmt.Sprint(excMessage[0])
+		} else if encoded, err := json.Marshal(excMessage); err == nil {
+			exception.Message = string(encoded)
+		}
+	case nil:
+	default:
+		exception.Message = fmt.Sprint(excMessage)
+	}
+
+	return exception
+}
+
+// readyStates are the celery task states after which a task won't change anymore.
+var readyStates = map[string]bool{
+	"SUCCESS": true,
+	"FAILURE": true,
+	"REVOKED": true,
+}
+
+// Ready reports whether the result is in a terminal state.
+func (rm *ResultMessage) Ready() bool {
+	return readyStates[rm.Status]
+}
+
+// normalizeTaskName trims surrounding whitespace from a task name and rejects
+// empty names or names containing control characters (e.g. a pasted newline),
+// which would produce tasks no worker can route.
+func normalizeTaskName(taskName string) (string, error) {
+	taskName = strings.TrimSpace(taskName)
+	if taskName == "" {
+		return "", fmt.Errorf("celery task name cannot be empty")
+	}
+	if strings.IndexFunc(taskName, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("celery task name %q cannot contain control characters", taskName)
+	}
+
+	return taskName, nil
+}
+
+// maxSafeInteger is the largest integer a JS number holds exactly (2^53 - 1).
+const maxSafeInteger = 1<<53 - 1
+
+// checkSafeIntegers checks task args hold no integer number beyond the JS safe range,
+// which has most likely been rounded already. Strings are left as is.
+func checkSafeIntegers(value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		if v > maxSafeInteger || v < -maxSafeInteger {
+			return fmt.Errorf("task argument %d is beyond 2^53 and may have lost precision, pass it as a numeric string", v)
+		}
+	case float64:
+		if math.Abs(v) > maxSafeInteger && v == math.Trunc(v) {
+			return fmt.Errorf("task argument %.0f is beyond 2^53 and may have lost precision, pass it as a numeric string", v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := checkSafeIntegers(item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if err := checkSafeIntegers(item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// encodeMessage builds the task message, serialized with marshal then base64 encoded.
+func encodeMessage(marshal func(interface{}) ([]byte, error), taskName string, messageId string, args []interface{}, kwargs map[string]interface{}, eta *string, expires *string, retries int) (string, error) {
+
+	// celery workers expect [] and {} rather than null
 	if args == nil {
 		args = make([]interface{}, 0)
 	}
+	if kwargs == nil {
+		kwargs = make(map[string]interface{})
+	}
 
 	tm := TaskMessage{
-		Task:   taskName,
-		Args:   args,
-		Kwargs: map[string]interface{}{},
-		ID:     messageId,
-		ETA:    nil,
+		Task:    taskName,
+		Args:    args,
+		Kwargs:  kwargs,
+		ID:      messageId,
+		ETA:     eta,
+		Expires: expires,
+		Retries: retries,
 	}
 
-	message, err := json.Marshal(tm)
+	message, err := marshal(tm)
 	if err != nil {
 		return "", err
 	}
@@ -145,13 +798,141 @@ func encodeMessage(taskName string, messageId string, args []interface{}) (strin
 	return encoded, nil
 }
 
-func newCeleryClient(redisClient *redis.Client) (ICeleryClient, error) {
-	brokerImpl := &RedisBroker{
-		redisClient: redisClient,
+// encodeProtobufBody validates a pre-encoded protobuf body given as a single base64 string.
+func encodeProtobufBody(args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("protobuf serializer expects a single base64 encoded body, got %d args", len(args))
+	}
+	body, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("protobuf serializer expects a base64 encoded string body")
+	}
+	message, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "", fmt.Errorf("protobuf body is not valid base64 %w", err)
 	}
 
-	return &CeleryClient{
-		brokerBackend: brokerImpl,
-	}, nil
+	return base64.StdEncoding.EncodeToString(message), nil
+}
 
+// decodeTaskMessage decodes a message like a worker would: the envelope, the base64 body,
+// then the body with the serializer of its content type.
+// Protobuf bodies can't be decoded without the worker schema, nil is returned for them.
+func decodeTaskMessage(raw []byte) (*TaskMessage, error) {
+	var message CeleryMessage
+	err := json.Unmarshal(raw, &message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message envelope; reason: %w", err)
+	}
+	if message.Properties.BodyEncoding != "base64" {
+		return nil, fmt.Errorf("unsupported body encoding %q", message.Properties.BodyEncoding)
+	}
+	body, err := base64.StdEncoding.DecodeString(message.Body)
+	if err != nil {
+		return nil, fmt.Errorf("body is not valid base64; reason: %w", err)
+	}
+
+	var unmarshal func([]byte, interface{}) error
+	switch message.ContentType {
+	case "application/json":
+		unmarshal = json.Unmarshal
+	case "application/x-msgpack":
+		unmarshal = msgpack.Unmarshal
+	case "application/x-yaml":
+		unmarshal = yaml.Unmarshal
+	case "application/x-protobuf":
+		return nil, nil
+	default:
+		registered, ok := registeredSerializerFor(message.ContentType)
+		if !ok {
+			return nil, fmt.Errorf("unsupported content type %q", message.ContentType)
+		}
+		unmarshal = registered.Unmarshal
+	}
+
+	var tm TaskMessage
+	err = unmarshal(body, &tm)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body; reason: %w", message.ContentType, err)
+	}
+
+	return &tm, nil
+}
+
+// decompressResult inflates result documents stored with celery result_compression.
+// Compression is detected from the gzip/zlib magic bytes, other values are returned as is.
+// With a limit (if not 0), inflating stops one byte past it, so a small compressed
+// value can't inflate to an unbounded document.
+// It returns whether the value was compressed.
+func decompressResult(val []byte, limit int) ([]byte, bool, error) {
+	var reader io.ReadCloser
+	var err error
+	switch {
+	case len(val) >= 2 && val[0] == 0x1f && val[1] == 0x8b:
+		reader, err = gzip.NewReader(bytes.NewReader(val))
+	case len(val) >= 2 && val[0] == 0x78 && (uint16(val[0])<<8|uint16(val[1]))%31 == 0:
+		reader, err = zlib.NewReader(bytes.NewReader(val))
+	default:
+		return val, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	defer reader.Close()
+
+	var limited io.Reader = reader
+	if limit > 0 {
+		limited = io.LimitReader(reader, int64(limit)+1)
+	}
+	val, err = io.ReadAll(limited)
+	return val, true, err
+}
+
+func newCeleryClient(brokerBackend BrokerBackend, opts *options, vu modules.VU) (ICeleryClient, error) {
+	cc := &CeleryClient{
+		brokerBackend: brokerBackend,
+		expiresIn:     opts.ExpiresIn.Duration,
+		serializer:    opts.Serializer,
+		route: queueRoute{
+			Queue:        opts.Queue.Name,
+			Exchange:     opts.Queue.Exchange,
+			ExchangeType: opts.Queue.ExchangeType,
+			RoutingKey:   opts.Queue.RoutingKey,
+		},
+
+		defaultHeaders:  opts.DefaultHeaders,
+		producerHeaders: map[string]interface{}{"producer": opts.ProducerID},
+
+		correlationEqualsTaskID: opts.CorrelationEqualsTaskID,
+		minimalProperties:       opts.MinimalProperties,
+		deliveryTagPrefix:       uuid.NewString(),
+		sequenceHeader:          opts.SequenceHeader,
+
+		maxResultBytes: opts.MaxResultBytes,
+		maxResultMode:  opts.MaxResultMode,
+		bareResults:    opts.BackendShape == backendShapeBare,
+
+		publishMaxRetries: opts.PublishMaxRetries,
+		publishBackoffMax: opts.PublishBackoffMax.Duration,
+
+		verifyPublish:      opts.VerifyPublish,
+		bigIntArgsAsString: opts.BigIntArgsAsString,
+	}
+	if opts.VUHeaders {
+		cc.vuHeaders = newVUHeaders(vu)
+	}
+
+	return cc, nil
+}
+
+// newVUHeaders returns a function reading the k6_vu and k6_iter headers from the VU state,
+// none are set outside of a VU iteration (e.g. in the init context).
+func newVUHeaders(vu modules.VU) func() map[string]interface{} {
+	return func() map[string]interface{} {
+		state := vu.State()
+		if state == nil {
+			return nil
+		}
+		return map[string]interface{}{"k6_vu": state.VUID, "k6_iter": state.Iteration}
+	}
 }
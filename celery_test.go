@@ -0,0 +1,371 @@
+package celery
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBroker is an in-memory BrokerBackend recording published messages and result reads.
+type fakeBroker struct {
+	mu        sync.Mutex
+	published map[string][][]byte
+	results   map[string]string
+	// publishErrs are returned by the next publish calls
+	publishErrs  []error
+	publishCalls int
+	getCalls     int
+	// getManyCalls are the task IDs of each GetMany call
+	getManyCalls [][]string
+}
+
+var _ BrokerBackend = &fakeBroker{}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{published: map[string][][]byte{}, results: map[string]string{}}
+}
+
+func (fb *fakeBroker) setResult(taskID string, document string) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.results[taskID] = document
+}
+
+// messages returns the messages published to a queue, in publishing order,
+// along with their decoded task message.
+func (fb *fakeBroker) messages(t *testing.T, queue string) ([]CeleryMessage, []*TaskMessage) {
+	t.Helper()
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	messages := make([]CeleryMessage, len(fb.published[queue]))
+	tasks := make([]*TaskMessage, len(fb.published[queue]))
+	for i, raw := range fb.published[queue] {
+		require.NoError(t, json.Unmarshal(raw, &messages[i]))
+		var err error
+		tasks[i], err = decodeTaskMessage(raw)
+		require.NoError(t, err)
+	}
+
+	return messages, tasks
+}
+
+func (fb *fakeBroker) Publish(ctx context.Context, message []byte, rawMessage string, queue string) error {
+	return fb.PublishMany(ctx, [][]byte{message}, queue)
+}
+
+func (fb *fakeBroker) PublishMany(ctx context.Context, messages [][]byte, queue string) error {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.publishCalls++
+	if len(fb.publishErrs) > 0 {
+		err := fb.publishErrs[0]
+		fb.publishErrs = fb.publishErrs[1:]
+		return err
+	}
+	fb.published[queue] = append(fb.published[queue], messages...)
+	return nil
+}
+
+func (fb *fakeBroker) PublishFanout(ctx context.Context, messages map[string][]byte) error {
+	for queue, message := range messages {
+		if err := fb.PublishMany(ctx, [][]byte{message}, queue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fb *fakeBroker) Peek(ctx context.Context, queue string) ([]byte, error) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	messages := fb.published[queue]
+	if len(messages) == 0 {
+		return nil, redis.Nil
+	}
+	return messages[len(messages)-1], nil
+}
+
+func (fb *fakeBroker) Get(ctx context.Context, taskID string) *redis.StringCmd {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.getCalls++
+	document, ok := fb.results[taskID]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(document, nil)
+}
+
+func (fb *fakeBroker) GetMany(ctx context.Context, taskIDs []string) *redis.SliceCmd {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.getManyCalls = append(fb.getManyCalls, append([]string(nil), taskIDs...))
+	values := make([]interface{}, len(taskIDs))
+	for i, taskID := range taskIDs {
+		if document, ok := fb.results[taskID]; ok {
+			values[i] = document
+		}
+	}
+	return redis.NewSliceResult(values, nil)
+}
+
+func (fb *fakeBroker) QueueLength(ctx context.Context, queue string) (int64, error) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return int64(len(fb.published[queue])), nil
+}
+
+func (fb *fakeBroker) Delete(ctx context.Context, taskIDs []string) (int64, error) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	var removed int64
+	for _, taskID := range taskIDs {
+		if _, ok := fb.results[taskID]; ok {
+			delete(fb.results, taskID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (fb *fakeBroker) ListResults(ctx context.Context, pattern string) ([]string, error) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	taskIDs := []string{}
+	for taskID := range fb.results {
+		if matched, _ := path.Match(pattern, taskID); matched {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	return taskIDs, nil
+}
+
+func (fb *fakeBroker) Close() error {
+	return nil
+}
+
+// newTestCeleryClient returns a celery client publishing to broker, with the default options.
+func newTestCeleryClient(t testing.TB, broker BrokerBackend) *CeleryClient {
+	t.Helper()
+	opts := &options{}
+	opts.applyDefaults()
+	client, err := newCeleryClient(broker, opts, nil)
+	require.NoError(t, err)
+	return client.(*CeleryClient)
+}
+
+func TestDecompressResultLimit(t *testing.T) {
+	t.Parallel()
+
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	_, err := writer.Write(make([]byte, 10<<20))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	inflated, isCompressed, err := decompressResult(compressed.Bytes(), 1024)
+	require.NoError(t, err)
+	assert.True(t, isCompressed)
+	assert.Len(t, inflated, 1025)
+
+	inflated, isCompressed, err = decompressResult(compressed.Bytes(), 0)
+	require.NoError(t, err)
+	assert.True(t, isCompressed)
+	assert.Len(t, inflated, 10<<20)
+}
+
+func TestMessageProperties(t *testing.T) {
+	t.Parallel()
+
+	for _, minimalProperties := range []bool{false, true} {
+		broker := newFakeBroker()
+		client := newTestCeleryClient(t, broker)
+		client.minimalProperties = minimalProperties
+
+		taskIDs := make([]string, 2)
+		for i := range taskIDs {
+			var err error
+			taskIDs[i], err = client.Delay(context.Background(), "celery", "tasks.add")
+			require.NoError(t, err)
+		}
+
+		messages, tasks := broker.messages(t, "celery")
+		require.Len(t, messages, 2)
+		for i, message := range messages {
+			assert.Equal(t, taskIDs[i], tasks[i].ID)
+			assert.NotEmpty(t, message.Properties.CorrelationID)
+			assert.NotEmpty(t, message.Properties.DeliveryTag)
+			assert.Equal(t, "base64", message.Properties.BodyEncoding)
+			assert.Equal(t, 2, message.Properties.DeliveryMode)
+			assert.Equal(t, "celery", message.Properties.DeliveryInfo.RoutingKey)
+			assert.Equal(t, minimalProperties, message.Properties.ReplyTo == "")
+		}
+		assert.NotEqual(t, messages[0].Properties.DeliveryTag, messages[1].Properties.DeliveryTag)
+	}
+
+	// delivery tags are unique across clients, e.g. of different VUs
+	first, second := newTestCeleryClient(t, newFakeBroker()), newTestCeleryClient(t, newFakeBroker())
+	_, firstMessage, err := first.newMessage("celery", "tasks.add", nil, TaskOptions{})
+	require.NoError(t, err)
+	_, secondMessage, err := second.newMessage("celery", "tasks.add", nil, TaskOptions{})
+	require.NoError(t, err)
+	var firstDecoded, secondDecoded CeleryMessage
+	require.NoError(t, json.Unmarshal(firstMessage, &firstDecoded))
+	require.NoError(t, json.Unmarshal(secondMessage, &secondDecoded))
+	assert.NotEqual(t, firstDecoded.Properties.DeliveryTag, secondDecoded.Properties.DeliveryTag)
+}
+
+func BenchmarkNewMessage(b *testing.B) {
+	for _, minimalProperties := range []bool{false, true} {
+		b.Run(fmt.Sprintf("minimalProperties=%t", minimalProperties), func(b *testing.B) {
+			client := newTestCeleryClient(b, newFakeBroker())
+			client.minimalProperties = minimalProperties
+			args := []interface{}{1, "two"}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := client.newMessage("celery", "tasks.add", args, TaskOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishRetries(t *testing.T) {
+	t.Parallel()
+
+	publishErr := errors.New("connection reset")
+	broker := newFakeBroker()
+	client := newTestCeleryClient(t, broker)
+	client.publishMaxRetries = 3
+	client.publishBackoffMax = 20 * time.Millisecond
+
+	broker.publishErrs = []error{publishErr, publishErr}
+	_, err := client.Delay(context.Background(), "celery", "tasks.add")
+	require.NoError(t, err)
+	assert.Equal(t, 3, broker.publishCalls)
+
+	broker.publishCalls = 0
+	broker.publishErrs = []error{publishErr, publishErr, publishErr, publishErr, publishErr}
+	_, err = client.Delay(context.Background(), "celery", "tasks.add")
+	require.ErrorIs(t, err, publishErr)
+	assert.Equal(t, 4, broker.publishCalls)
+
+	// retries stop as soon as the context is done
+	broker.publishCalls = 0
+	broker.publishErrs = make([]error, 100)
+	for i := range broker.publishErrs {
+		broker.publishErrs[i] = publishErr
+	}
+	client.publishMaxRetries = 100
+	client.publishBackoffMax = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.Delay(ctx, "celery", "tasks.add")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Less(t, broker.publishCalls, 100)
+}
+
+// staleHeadBroker peeks a fixed message instead of the queue head.
+type staleHeadBroker struct {
+	*fakeBroker
+	head []byte
+}
+
+func (sb *staleHeadBroker) Peek(ctx context.Context, queue string) ([]byte, error) {
+	return sb.head, nil
+}
+
+func TestVerifyPublish(t *testing.T) {
+	t.Parallel()
+
+	broker := newFakeBroker()
+	client := newTestCeleryClient(t, broker)
+	client.verifyPublish = true
+	_, err := client.Delay(context.Background(), "celery", "tasks.add")
+	require.NoError(t, err)
+
+	staleID, stale, err := client.newMessage("celery", "tasks.add", nil, TaskOptions{})
+	require.NoError(t, err)
+	client.brokerBackend = &staleHeadBroker{fakeBroker: broker, head: stale}
+	taskID, err := client.Delay(context.Background(), "celery", "tasks.add")
+	require.Error(t, err)
+	assert.Empty(t, taskID)
+	assert.Contains(t, err.Error(), fmt.Sprintf("queue celery head is task %s (tasks.add), expected published task", staleID))
+
+	client.brokerBackend = &staleHeadBroker{fakeBroker: broker, head: []byte("garbage")}
+	_, err = client.Delay(context.Background(), "celery", "tasks.add")
+	assert.ErrorContains(t, err, "can't be decoded")
+}
+
+func TestApplyAsyncEnvelope(t *testing.T) {
+	t.Parallel()
+
+	broker := newFakeBroker()
+	client := newTestCeleryClient(t, broker)
+
+	taskID, err := client.ApplyAsync(context.Background(), "worker-queue", "tasks.add", []interface{}{1}, TaskOptions{})
+	require.NoError(t, err)
+	delayID, err := client.Delay(context.Background(), "worker-queue", "tasks.mul", 2)
+	require.NoError(t, err)
+
+	assert.Empty(t, broker.published["tasks.add"])
+	messages, tasks := broker.messages(t, "worker-queue")
+	require.Len(t, messages, 2)
+	assert.Equal(t, taskID, tasks[0].ID)
+	assert.Equal(t, "tasks.add", tasks[0].Task)
+	assert.Equal(t, "worker-queue", messages[0].Properties.DeliveryInfo.RoutingKey)
+	assert.Equal(t, delayID, tasks[1].ID)
+	assert.Equal(t, "tasks.mul", tasks[1].Task)
+	assert.Equal(t, "worker-queue", messages[1].Properties.DeliveryInfo.RoutingKey)
+}
+
+// TestDecodeTruncatedResult isn't parallel as it counts allocations.
+func TestDecodeTruncatedResult(t *testing.T) {
+	client := newTestCeleryClient(t, newFakeBroker())
+	client.maxResultBytes = 1024
+	client.maxResultMode = maxResultModeTruncate
+	items := make([]string, 10000)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"item": %d}`, i)
+	}
+	document := []byte(`{"task_id": "task", "status": "SUCCESS", "result": [` + strings.Join(items, ",") +
+		`], "date_done": "2024-01-01T00:00:00", "retries": 2}`)
+
+	result, err := client.decodeResult("task", document)
+	require.NoError(t, err)
+	assert.Equal(t, &ResultMessage{ID: "task", Status: "SUCCESS", DateDone: "2024-01-01T00:00:00", Retries: 2, Truncated: true}, result)
+
+	// the payload isn't decoded, a decoded one would allocate its 10000 items
+	allocs := testing.AllocsPerRun(10, func() {
+		_, _ = client.decodeResult("task", document)
+	})
+	assert.Less(t, allocs, float64(100))
+
+	_, err = client.decodeResult("task", append(document[:len(document)-1:len(document)-1], ','))
+	assert.Error(t, err)
+
+	client.bareResults = true
+	result, err = client.decodeResult("task", []byte(`[`+strings.Join(items, ",")+`]`))
+	require.NoError(t, err)
+	assert.Equal(t, &ResultMessage{ID: "task", Status: "SUCCESS", Truncated: true}, result)
+	_, err = client.decodeResult("task", []byte(`[`+strings.Join(items, ",")))
+	assert.EqualError(t, err, "result document is not valid JSON")
+}
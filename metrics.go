@@ -0,0 +1,95 @@
+package celery
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/metrics"
+)
+
+// celeryMetrics holds the custom metrics emitted by the extension.
+type celeryMetrics struct {
+	RedisCmdDuration *metrics.Metric
+	TasksSubmitted   *metrics.Metric
+	TimeToResult     *metrics.Metric
+}
+
+// registerMetrics registers the extension metrics in the k6 registry.
+// It has to be called in the init context.
+func registerMetrics(vu modules.VU) (celeryMetrics, error) {
+	var err error
+	registry := vu.InitEnv().Registry
+	m := celeryMetrics{}
+
+	if m.RedisCmdDuration, err = registry.NewMetric(
+		"celery_redis_cmd_duration", metrics.Trend, metrics.Time); err != nil {
+		return m, err
+	}
+
+	if m.TasksSubmitted, err = registry.NewMetric(
+		"celery_tasks_submitted", metrics.Counter); err != nil {
+		return m, err
+	}
+
+	if m.TimeToResult, err = registry.NewMetric(
+		"celery_time_to_result", metrics.Trend, metrics.Time); err != nil {
+		return m, err
+	}
+
+	return m, nil
+}
+
+// pushSample emits a sample tagged with the current VU tags and the given extra tags.
+// Samples are dropped outside of a VU iteration (e.g. in the init context).
+func pushSample(vu modules.VU, metric *metrics.Metric, value float64, extraTags map[string]string) {
+	state := vu.State()
+	if state == nil {
+		return
+	}
+
+	ctm := state.Tags.GetCurrentValues()
+	metrics.PushIfNotDone(vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   ctm.Tags.WithTagsFromMap(extraTags),
+		},
+		Time:     time.Now(),
+		Metadata: ctm.Metadata,
+		Value:    value,
+	})
+}
+
+// commandMetricsHook is a go-redis hook measuring each command duration,
+// emitted as a celery_redis_cmd_duration trend tagged with the command name.
+type commandMetricsHook struct {
+	vu      modules.VU
+	metrics celeryMetrics
+}
+
+var _ redis.Hook = &commandMetricsHook{}
+
+func (h *commandMetricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *commandMetricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		pushSample(h.vu, h.metrics.RedisCmdDuration, metrics.D(time.Since(start)),
+			map[string]string{"command": cmd.Name()})
+		return err
+	}
+}
+
+func (h *commandMetricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		pushSample(h.vu, h.metrics.RedisCmdDuration, metrics.D(time.Since(start)),
+			map[string]string{"command": "pipeline"})
+		return err
+	}
+}
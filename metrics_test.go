@@ -0,0 +1,81 @@
+package celery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandMetrics(t *testing.T) {
+	t.Parallel()
+
+	vu, client, _ := newTestClient(t, `{commandMetrics: true}`)
+
+	_, err := client.Delay("tasks.add")
+	require.NoError(t, err)
+	_, err = client.TaskCompleted("missing")
+	require.NoError(t, err)
+
+	commands := map[string]int{}
+	for _, sample := range vu.metricSamples("celery_redis_cmd_duration") {
+		command, _ := sample.Tags.Get("command")
+		commands[command]++
+		assert.Positive(t, sample.Value)
+	}
+	assert.Equal(t, 1, commands["lpush"])
+	assert.Equal(t, 1, commands["get"])
+}
+
+func TestCommandMetricsDisabled(t *testing.T) {
+	t.Parallel()
+
+	vu, client, _ := newTestClient(t, `{}`)
+
+	_, err := client.Delay("tasks.add")
+	require.NoError(t, err)
+	assert.Empty(t, vu.metricSamples("celery_redis_cmd_duration"))
+}
+
+func TestTasksSubmittedPerQueue(t *testing.T) {
+	t.Parallel()
+
+	vu, client, _ := newTestClient(t, `{}`)
+
+	_, err := client.DelayTo("high", "tasks.add")
+	require.NoError(t, err)
+	_, err = client.DelayTo("high", "tasks.add")
+	require.NoError(t, err)
+	_, err = client.DelayTo("low", "tasks.add")
+	require.NoError(t, err)
+
+	submitted := map[string]float64{}
+	for _, sample := range vu.metricSamples("celery_tasks_submitted") {
+		queue, _ := sample.Tags.Get("queue")
+		submitted[queue] += sample.Value
+	}
+	assert.Equal(t, map[string]float64{"high": 2, "low": 1}, submitted)
+}
+
+func TestTimeToResult(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+	broker := newFakeBroker()
+	client := vu.newFakeCelery(t, broker, 10*time.Millisecond)
+
+	taskID, err := client.Delay("tasks.add")
+	require.NoError(t, err)
+	time.AfterFunc(200*time.Millisecond, func() { broker.setResult(taskID, `{"status": "STARTED"}`) })
+	time.AfterFunc(300*time.Millisecond, func() { broker.setResult(taskID, `{"status": "SUCCESS"}`) })
+
+	completed, err := client.WaitForTaskCompleted(taskID)
+	require.NoError(t, err)
+	require.True(t, completed)
+
+	// measured up to the first result document observed, not to completion
+	samples := vu.metricSamples("celery_time_to_result")
+	require.Len(t, samples, 1)
+	assert.InDelta(t, 200, samples[0].Value, 60)
+}
@@ -2,7 +2,17 @@ package celery
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -10,10 +20,49 @@ import (
 type RedisClient interface {
 	LPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
 	Get(ctx context.Context, key string) *redis.StringCmd
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+	Pipeline() redis.Pipeliner
+	TxPipeline() redis.Pipeliner
+	TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+	Unlink(ctx context.Context, keys ...string) *redis.IntCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SCard(ctx context.Context, key string) *redis.IntCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	LIndex(ctx context.Context, key string, index int64) *redis.StringCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	Close() error
+}
+
+// prioritySteps and prioritySeparator mirror kombu's redis transport defaults.
+// Tasks sent with a priority are pushed to "<queue>\x06\x16<step>" lists,
+// step 0 being the bare queue name.
+var prioritySteps = []int{0, 3, 6, 9}
+
+const prioritySeparator = "\x06\x16"
+
+func priorityQueueName(queue string, step int) string {
+	if step == 0 {
+		return queue
+	}
+	return fmt.Sprintf("%s%s%d", queue, prioritySeparator, step)
 }
 
 type RedisBroker struct {
 	redisClient RedisClient
+	// resultClient reads results, it's redisClient unless pools are isolated
+	resultClient RedisClient
+	// queueType is the redis type of queue keys: list (celery default) or set
+	queueType string
+	// queueTTL is the expiry set on the queue key after each publish (if set)
+	queueTTL time.Duration
+	// resultKeyPrefix is prepended to task IDs to get their result key
+	resultKeyPrefix string
+	// resultKeyHashtag wraps task IDs in a {hashtag} in result keys, for cluster slot colocation
+	resultKeyHashtag bool
+	// cluster is set when keys can be spread across cluster slots
+	cluster bool
 }
 
 type SentinelEnvConfig struct {
@@ -21,21 +70,141 @@ type SentinelEnvConfig struct {
 	Port int    `json:"port"`
 }
 
-func NewRedisBrokerBackend(client RedisClient) *RedisBroker {
+func NewRedisBrokerBackend(client RedisClient, resultClient RedisClient, opts *options) *RedisBroker {
 	return &RedisBroker{
-		redisClient: client,
+		redisClient:      client,
+		resultClient:     resultClient,
+		queueType:        opts.QueueType,
+		queueTTL:         opts.QueueTTL.Duration,
+		resultKeyPrefix:  *opts.ResultKeyPrefix,
+		resultKeyHashtag: opts.ResultKeyHashtag,
+		cluster:          len(opts.ClusterAddrs) > 0,
+	}
+}
+
+// newTLSConfig builds the TLS configuration of redis connections, nil if TLS is disabled.
+func newTLSConfig(opts *options) (*tls.Config, error) {
+	if !opts.TLS {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// opt-in, for self-signed test setups
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CACertFile != "" {
+		caCert, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read caCertFile; reason: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no PEM certificate found in caCertFile %s", opts.CACertFile)
+		}
+	}
+
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate; reason: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// LastError is the last error returned by a Redis command.
+type LastError struct {
+	Message string `js:"message"`
+	// Time is the RFC3339 time the error occurred.
+	Time string `js:"time"`
+}
+
+// lastErrorHook is a go-redis hook recording the last command error, cleared by the next
+// successful command. A missing key (redis.Nil) is not an error.
+type lastErrorHook struct {
+	last atomic.Pointer[LastError]
+}
+
+var _ redis.Hook = &lastErrorHook{}
+
+func (h *lastErrorHook) record(err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		h.last.Store(nil)
+		return
+	}
+	h.last.Store(&LastError{Message: err.Error(), Time: time.Now().Format(time.RFC3339Nano)})
+}
+
+func (h *lastErrorHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *lastErrorHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		h.record(err)
+		return err
 	}
 }
 
-func NewRedisClient(opts *options) *redis.Client {
+func (h *lastErrorHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		h.record(err)
+		return err
+	}
+}
+
+// NewRedisClient creates a redis client, poolSize 0 uses the go-redis default pool size (10 per CPU).
+func NewRedisClient(opts *options, poolSize int) (redis.UniversalClient, error) {
+
+	if len(opts.ClusterAddrs) > 0 {
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        opts.ClusterAddrs,
+			PoolSize:     poolSize,
+			MinIdleConns: opts.MinIdleConns,
+			MaxRetries:   opts.MaxRetries,
+			TLSConfig:    opts.tlsConfig,
+			Username:     opts.Username,
+			Password:     opts.Password,
+
+			ContextTimeoutEnabled: true,
+		}), nil
+	}
 
 	if len(opts.SentinelAddrs) == 0 {
 		redisOpts, err := redis.ParseURL(opts.Url)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("invalid redis url; reason: %w", err)
+		}
+		// needed for checkTimeout deadlines to apply to commands
+		redisOpts.ContextTimeoutEnabled = true
+		if poolSize > 0 {
+			redisOpts.PoolSize = poolSize
+		}
+		if opts.MinIdleConns > 0 {
+			redisOpts.MinIdleConns = opts.MinIdleConns
+		}
+		if opts.MaxRetries != 0 {
+			redisOpts.MaxRetries = opts.MaxRetries
+		}
+		if opts.tlsConfig != nil {
+			// the server name is taken from the dialed address
+			redisOpts.TLSConfig = opts.tlsConfig
+		}
+		// explicit credentials take precedence over the URL ones
+		if opts.Username != "" {
+			redisOpts.Username = opts.Username
+		}
+		if opts.Password != "" {
+			redisOpts.Password = opts.Password
 		}
 
-		return redis.NewClient(redisOpts)
+		return redis.NewClient(redisOpts), nil
 	} else {
 
 		failOverOptions := &redis.FailoverOptions{
@@ -45,23 +214,270 @@ func NewRedisClient(opts *options) *redis.Client {
 			ReadTimeout:     opts.GetRetryInterval.Duration,
 			WriteTimeout:    opts.GetRetryInterval.Duration,
 			MaxRetryBackoff: opts.GetRetryInterval.Duration,
+			PoolSize:        poolSize,
+			MinIdleConns:    opts.MinIdleConns,
+			MaxRetries:      opts.MaxRetries,
+			TLSConfig:       opts.tlsConfig,
+			Username:        opts.Username,
+			Password:        opts.Password,
+
+			ContextTimeoutEnabled: true,
 		}
 
-		return redis.NewFailoverClient(failOverOptions)
+		return redis.NewFailoverClient(failOverOptions), nil
 	}
 
 }
 
 func (rb *RedisBroker) Publish(ctx context.Context, message []byte, rawMessage string, queue string) error {
-	err := rb.redisClient.LPush(ctx, queue, message).Err()
-	if err != nil {
+	return rb.PublishMany(ctx, [][]byte{message}, queue)
+}
+
+// PublishMany publishes messages with a single LPUSH (SADD for set queues),
+// the first message being the first consumed.
+func (rb *RedisBroker) PublishMany(ctx context.Context, messages [][]byte, queue string) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, len(messages))
+	for i, message := range messages {
+		values[i] = message
+	}
+
+	if rb.queueTTL > 0 {
+		// the expiry is refreshed on each publish, so only queues abandoned for queueTTL expire.
+		// Both run in a MULTI transaction: a failed EXPIRE must not leave pushed messages
+		// that a publish retry would push again.
+		_, err := rb.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			rb.push(ctx, pipe, queue, values...)
+			pipe.Expire(ctx, queue, rb.queueTTL)
+			return nil
+		})
 		return err
 	}
 
-	return nil
+	if rb.queueType == queueTypeSet {
+		// custom consumers reading sets get messages deduplicated, but unordered
+		return rb.redisClient.SAdd(ctx, queue, values...).Err()
+	}
+	return rb.redisClient.LPush(ctx, queue, values...).Err()
+}
+
+// push queues a push of values to the queue: SADD for set queues, LPUSH otherwise.
+func (rb *RedisBroker) push(ctx context.Context, pipe redis.Pipeliner, queue string, values ...interface{}) {
+	if rb.queueType == queueTypeSet {
+		pipe.SAdd(ctx, queue, values...)
+	} else {
+		pipe.LPush(ctx, queue, values...)
+	}
+}
+
+// PublishFanout publishes a message to each queue in a single MULTI transaction round-trip.
+// It's not atomic: a cluster runs one transaction per hash slot, and Redis doesn't roll back
+// the pushes of a transaction when one of them fails, so a failed fanout may have reached some queues.
+func (rb *RedisBroker) PublishFanout(ctx context.Context, messages map[string][]byte) error {
+	pipe := rb.redisClient.TxPipeline()
+	for queue, message := range messages {
+		rb.push(ctx, pipe, queue, message)
+		if rb.queueTTL > 0 {
+			pipe.Expire(ctx, queue, rb.queueTTL)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// resultKey returns the result key of a task ID: <prefix><taskID>, or <prefix>{<taskID>} with hashtags.
+func (rb *RedisBroker) resultKey(taskID string) string {
+	if rb.resultKeyHashtag {
+		return rb.resultKeyPrefix + "{" + taskID + "}"
+	}
+	return rb.resultKeyPrefix + taskID
+}
+
+// resultKeys returns the result keys of task IDs.
+func (rb *RedisBroker) resultKeys(taskIDs []string) []string {
+	keys := make([]string, len(taskIDs))
+	for i, taskID := range taskIDs {
+		keys[i] = rb.resultKey(taskID)
+	}
+	return keys
+}
+
+// resultTaskID returns the task ID of a result key.
+func (rb *RedisBroker) resultTaskID(key string) string {
+	taskID := strings.TrimPrefix(key, rb.resultKeyPrefix)
+	if rb.resultKeyHashtag {
+		taskID = strings.TrimSuffix(strings.TrimPrefix(taskID, "{"), "}")
+	}
+	return taskID
 }
 
 func (rb *RedisBroker) Get(ctx context.Context, taskID string) *redis.StringCmd {
-	val := rb.redisClient.Get(ctx, taskID)
+	val := rb.resultClient.Get(ctx, rb.resultKey(taskID))
 	return val
 }
+
+func (rb *RedisBroker) GetMany(ctx context.Context, taskIDs []string) *redis.SliceCmd {
+	if !rb.cluster {
+		return rb.resultClient.MGet(ctx, rb.resultKeys(taskIDs)...)
+	}
+
+	// result keys hash to different slots, a single MGET would fail with CROSSSLOT
+	pipe := rb.resultClient.Pipeline()
+	cmds := make([]*redis.StringCmd, len(taskIDs))
+	for i, key := range rb.resultKeys(taskIDs) {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	_, _ = pipe.Exec(ctx)
+
+	values := make([]interface{}, len(cmds))
+	for i, cmd := range cmds {
+		document, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return redis.NewSliceResult(nil, err)
+		}
+		values[i] = document
+	}
+
+	return redis.NewSliceResult(values, nil)
+}
+
+// Peek returns the message at the head of the queue, the last published one.
+func (rb *RedisBroker) Peek(ctx context.Context, queue string) ([]byte, error) {
+	return rb.redisClient.LIndex(ctx, queue, 0).Bytes()
+}
+
+// QueueLength sums the length of the queue list and its priority lists,
+// or returns the set cardinality for set queues.
+// A missing key (e.g. a queue fully consumed) counts as an empty list.
+func (rb *RedisBroker) QueueLength(ctx context.Context, queue string) (int64, error) {
+	if rb.queueType == queueTypeSet {
+		return rb.redisClient.SCard(ctx, queue).Result()
+	}
+
+	pipe := rb.redisClient.Pipeline()
+	cmds := make([]*redis.IntCmd, len(prioritySteps))
+	for i, step := range prioritySteps {
+		cmds[i] = pipe.LLen(ctx, priorityQueueName(queue, step))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var length int64
+	for _, cmd := range cmds {
+		length += cmd.Val()
+	}
+
+	return length, nil
+}
+
+// Delete removes task results, using non-blocking UNLINK when the server supports it.
+func (rb *RedisBroker) Delete(ctx context.Context, taskIDs []string) (int64, error) {
+	if len(taskIDs) == 0 {
+		return 0, nil
+	}
+
+	keys := rb.resultKeys(taskIDs)
+	if rb.cluster {
+		return rb.deleteEach(ctx, keys)
+	}
+
+	removed, err := rb.resultClient.Unlink(ctx, keys...).Result()
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "unknown command") {
+		// UNLINK is only available since redis 4.0
+		return rb.resultClient.Del(ctx, keys...).Result()
+	}
+
+	return removed, err
+}
+
+// scanCount is the number of keys hinted to each SCAN iteration.
+const scanCount = 1000
+
+// ListResults returns the IDs of tasks having a result key matching the pattern,
+// using non-blocking SCAN iterations. The pattern is matched after the result key prefix.
+func (rb *RedisBroker) ListResults(ctx context.Context, pattern string) ([]string, error) {
+	match := rb.resultKey(pattern)
+	taskIDs := []string{}
+	scan := func(ctx context.Context, client RedisClient) error {
+		iter := client.Scan(ctx, 0, match, scanCount).Iterator()
+		for iter.Next(ctx) {
+			taskIDs = append(taskIDs, rb.resultTaskID(iter.Val()))
+		}
+		return iter.Err()
+	}
+
+	clusterClient, ok := rb.resultClient.(*redis.ClusterClient)
+	if !ok {
+		return taskIDs, scan(ctx, rb.resultClient)
+	}
+
+	// each master only scans its own slots
+	var mu sync.Mutex
+	err := clusterClient.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return scan(ctx, master)
+	})
+
+	return taskIDs, err
+}
+
+// deleteEach removes keys with one UNLINK per key, as keys spread across cluster slots
+// can't be removed in a single command.
+func (rb *RedisBroker) deleteEach(ctx context.Context, keys []string) (int64, error) {
+	pipe := rb.resultClient.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Unlink(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	for _, cmd := range cmds {
+		removed += cmd.Val()
+	}
+
+	return removed, nil
+}
+
+// Close closes the redis clients, releasing their connections.
+func (rb *RedisBroker) Close() error {
+	err := rb.redisClient.Close()
+	if rb.resultClient != rb.redisClient {
+		err = errors.Join(err, rb.resultClient.Close())
+	}
+	return err
+}
+
+// Probe checks the backend is reachable and writable with a short lived write then read.
+func (rb *RedisBroker) Probe(ctx context.Context) error {
+	key := "xk6-celery-probe-" + uuid.NewString()
+	value := uuid.NewString()
+	err := rb.resultClient.Set(ctx, key, value, time.Minute).Err()
+	if err != nil {
+		return fmt.Errorf("backend is not writable; reason: %w", err)
+	}
+	defer rb.resultClient.Del(ctx, key)
+
+	read, err := rb.resultClient.Get(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("backend is not readable; reason: %w", err)
+	}
+	if read != value {
+		return fmt.Errorf("backend probe read %q, expected %q", read, value)
+	}
+
+	return nil
+}
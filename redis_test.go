@@ -0,0 +1,294 @@
+package celery
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRedisBroker returns a broker connected to a new miniredis server, with the options
+// (defaults applied) edited by setOptions.
+func newTestRedisBroker(t *testing.T, setOptions func(opts *options)) (*RedisBroker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	opts := &options{}
+	if setOptions != nil {
+		setOptions(opts)
+	}
+	opts.applyDefaults()
+	return NewRedisBrokerBackend(client, client, opts), mr
+}
+
+func TestPublishSetQueue(t *testing.T) {
+	t.Parallel()
+
+	broker, mr := newTestRedisBroker(t, func(opts *options) { opts.QueueType = queueTypeSet })
+	ctx := context.Background()
+
+	require.NoError(t, broker.Publish(ctx, []byte("message"), "", "celery"))
+	require.NoError(t, broker.PublishMany(ctx, [][]byte{[]byte("message"), []byte("other")}, "celery"))
+
+	assert.True(t, mr.Exists("celery"))
+	members, err := mr.Members("celery")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"message", "other"}, members)
+
+	length, err := broker.QueueLength(ctx, "celery")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), length)
+}
+
+func TestResultKeyHashtag(t *testing.T) {
+	t.Parallel()
+
+	broker, mr := newTestRedisBroker(t, func(opts *options) { opts.ResultKeyHashtag = true })
+	ctx := context.Background()
+	require.NoError(t, mr.Set("celery-task-meta-{task}", `{"status": "SUCCESS"}`))
+	assert.Equal(t, "celery-task-meta-{task}", broker.resultKey("task"))
+
+	document, err := broker.Get(ctx, "task").Result()
+	require.NoError(t, err)
+	assert.Equal(t, `{"status": "SUCCESS"}`, document)
+
+	documents, err := broker.GetMany(ctx, []string{"task", "missing"}).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{`{"status": "SUCCESS"}`, nil}, documents)
+
+	taskIDs, err := broker.ListResults(ctx, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task"}, taskIDs)
+
+	removed, err := broker.Delete(ctx, []string{"task"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	assert.False(t, mr.Exists("celery-task-meta-{task}"))
+}
+
+func TestQueueTTL(t *testing.T) {
+	t.Parallel()
+
+	for _, queueType := range []string{queueTypeList, queueTypeSet} {
+		broker, mr := newTestRedisBroker(t, func(opts *options) {
+			opts.QueueType = queueType
+			opts.QueueTTL.Duration = 10 * time.Minute
+		})
+		ctx := context.Background()
+
+		require.NoError(t, broker.Publish(ctx, []byte("message"), "", "celery"))
+		assert.Equal(t, 10*time.Minute, mr.TTL("celery"), queueType)
+
+		mr.FastForward(5 * time.Minute)
+		require.NoError(t, broker.PublishFanout(ctx, map[string][]byte{"celery": []byte("other"), "high": []byte("other")}))
+		assert.Equal(t, 10*time.Minute, mr.TTL("celery"), queueType)
+		assert.Equal(t, 10*time.Minute, mr.TTL("high"), queueType)
+
+		length, err := broker.QueueLength(ctx, "celery")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), length, queueType)
+	}
+}
+
+func TestPublishFanoutNotAtomic(t *testing.T) {
+	t.Parallel()
+
+	broker, mr := newTestRedisBroker(t, nil)
+	ctx := context.Background()
+	// pushing to a string key fails, the transaction still pushes to the other queues
+	require.NoError(t, mr.Set("high", "not a list"))
+
+	err := broker.PublishFanout(ctx, map[string][]byte{"celery": []byte("message"), "high": []byte("message")})
+	assert.ErrorContains(t, err, "WRONGTYPE")
+	queued, err := mr.List("celery")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message"}, queued)
+}
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM files in dir.
+func writeTestCertificate(t *testing.T, dir string) (certFile string, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+	notPEM := filepath.Join(dir, "not.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	tlsConfig, err := newTLSConfig(&options{CACertFile: certFile})
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig, "tls disabled")
+
+	tlsConfig, err = newTLSConfig(&options{TLS: true})
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig.RootCAs, "system roots")
+	assert.Empty(t, tlsConfig.Certificates)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	tlsConfig, err = newTLSConfig(&options{TLS: true, InsecureSkipVerify: true, CACertFile: certFile, CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Len(t, tlsConfig.Certificates, 1)
+
+	_, err = newTLSConfig(&options{TLS: true, CACertFile: filepath.Join(dir, "missing.pem")})
+	assert.ErrorContains(t, err, "unable to read caCertFile")
+	_, err = newTLSConfig(&options{TLS: true, CACertFile: notPEM})
+	assert.ErrorContains(t, err, "no PEM certificate found in caCertFile")
+	_, err = newTLSConfig(&options{TLS: true, CertFile: certFile})
+	assert.ErrorContains(t, err, "unable to load client certificate")
+	_, err = newTLSConfig(&options{TLS: true, CertFile: certFile, KeyFile: notPEM})
+	assert.ErrorContains(t, err, "unable to load client certificate")
+}
+
+func TestRedisClientTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	opts := &options{Url: "redis://localhost:6379", TLS: true, InsecureSkipVerify: true}
+	var err error
+	opts.tlsConfig, err = newTLSConfig(opts)
+	require.NoError(t, err)
+
+	client, err := NewRedisClient(opts, 0)
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Same(t, opts.tlsConfig, client.(*redis.Client).Options().TLSConfig)
+
+	cluster := *opts
+	cluster.ClusterAddrs = []string{"localhost:7000", "localhost:7001"}
+	client, err = NewRedisClient(&cluster, 0)
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Same(t, opts.tlsConfig, client.(*redis.ClusterClient).Options().TLSConfig)
+
+	sentinel := *opts
+	sentinel.SentinelAddrs = []string{"localhost:26379"}
+	sentinel.MasterName = "mymaster"
+	sentinel.GetRetryInterval = &Duration{Duration: 50 * time.Millisecond}
+	client, err = NewRedisClient(&sentinel, 0)
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Same(t, opts.tlsConfig, client.(*redis.Client).Options().TLSConfig)
+}
+
+// commandsHook is a go-redis hook recording the name of the commands processed, pipelined ones included.
+type commandsHook struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (h *commandsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *commandsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.record(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *commandsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.record(cmds...)
+		return next(ctx, cmds)
+	}
+}
+
+func (h *commandsHook) record(cmds ...redis.Cmder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, cmd := range cmds {
+		h.commands = append(h.commands, cmd.Name())
+	}
+}
+
+// reset returns the commands recorded so far and forgets them.
+func (h *commandsHook) reset() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	commands := h.commands
+	h.commands = nil
+	return commands
+}
+
+func TestClusterResults(t *testing.T) {
+	t.Parallel()
+
+	// miniredis answers CLUSTER SLOTS as a single node cluster
+	mr := miniredis.RunT(t)
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}})
+	t.Cleanup(func() { _ = client.Close() })
+	hook := &commandsHook{}
+	client.AddHook(hook)
+	opts := &options{ClusterAddrs: []string{mr.Addr()}}
+	opts.applyDefaults()
+	broker := NewRedisBrokerBackend(client, client, opts)
+	ctx := context.Background()
+
+	require.NoError(t, mr.Set(defaultResultKeyPrefix+"task-1", `{"status": "SUCCESS"}`))
+	require.NoError(t, mr.Set(defaultResultKeyPrefix+"task-2", `{"status": "STARTED"}`))
+	require.NoError(t, mr.Set("other", "value"))
+	hook.reset()
+
+	// keys of different slots can't be read with a single MGET
+	values, err := broker.GetMany(ctx, []string{"task-1", "missing", "task-2"}).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{`{"status": "SUCCESS"}`, nil, `{"status": "STARTED"}`}, values)
+	assert.Equal(t, []string{"get", "get", "get"}, hook.reset())
+
+	taskIDs, err := broker.ListResults(ctx, "*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"task-1", "task-2"}, taskIDs)
+
+	hook.reset()
+	removed, err := broker.Delete(ctx, []string{"task-1", "missing", "task-2"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+	assert.Equal(t, []string{"unlink", "unlink", "unlink"}, hook.reset())
+	assert.False(t, mr.Exists(defaultResultKeyPrefix+"task-1"))
+	assert.True(t, mr.Exists("other"))
+
+	taskIDs, err = broker.ListResults(ctx, "*")
+	require.NoError(t, err)
+	assert.Empty(t, taskIDs)
+}
@@ -0,0 +1,64 @@
+package celery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const resultSchemaURL = "resultSchema.json"
+
+// compileResultSchema compiles the resultSchema option, given either as a
+// JSON schema object or as its string representation.
+// It returns nil when no schema is configured.
+func compileResultSchema(rawSchema json.RawMessage) (*jsonschema.Schema, error) {
+	if len(rawSchema) == 0 {
+		return nil, nil
+	}
+
+	var schemaStr string
+	if err := json.Unmarshal(rawSchema, &schemaStr); err == nil {
+		rawSchema = json.RawMessage(schemaStr)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	err := compiler.AddResource(resultSchemaURL, bytes.NewReader(rawSchema))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load result schema %w", err)
+	}
+
+	schema, err := compiler.Compile(resultSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile result schema %w", err)
+	}
+
+	return schema, nil
+}
+
+// validateAgainstSchema validates a task result against schema and returns the
+// validation errors, formatted as "<instance location>: <message>".
+func validateAgainstSchema(schema *jsonschema.Schema, result interface{}) ([]string, error) {
+	err := schema.Validate(result)
+	if err == nil {
+		return []string{}, nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	var validationErrors []string
+	for _, basicErr := range validationErr.BasicOutput().Errors {
+		location := basicErr.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		validationErrors = append(validationErrors, fmt.Sprintf("%s: %s", location, basicErr.Error))
+	}
+
+	return validationErrors, nil
+}
@@ -0,0 +1,44 @@
+package celery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateResult(t *testing.T) {
+	t.Parallel()
+
+	_, client, mr := newTestClient(t, `{
+		timeout: "1s",
+		resultSchema: {
+			type: "object",
+			properties: { total: { type: "integer" } },
+			required: ["total"],
+		},
+	}`)
+	seedResult(t, mr, "conforming", map[string]interface{}{"status": "SUCCESS", "result": map[string]interface{}{"total": 42}})
+	seedResult(t, mr, "nonconforming", map[string]interface{}{"status": "SUCCESS", "result": map[string]interface{}{"total": "42"}})
+
+	validationErrors, err := client.ValidateResult("conforming")
+	require.NoError(t, err)
+	assert.Empty(t, validationErrors)
+
+	validationErrors, err = client.ValidateResult("nonconforming")
+	require.NoError(t, err)
+	assert.Contains(t, validationErrors, "/total: expected integer, but got string")
+}
+
+func TestCompileResultSchemaString(t *testing.T) {
+	t.Parallel()
+
+	schema, err := compileResultSchema([]byte(`"{\"type\": \"string\"}"`))
+	require.NoError(t, err)
+	validationErrors, err := validateAgainstSchema(schema, 42.0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, validationErrors)
+
+	_, err = compileResultSchema([]byte(`{"type": 42}`))
+	assert.Error(t, err)
+}
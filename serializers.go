@@ -0,0 +1,64 @@
+package celery
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Serializer encodes task bodies in a format the workers accept with the matching
+// kombu serializer (registered with kombu.serialization.register on the worker side).
+type Serializer struct {
+	// ContentType identifies the serializer in messages, e.g. "application/x-cbor".
+	ContentType string
+	// ContentEncoding is "binary" for binary formats, "utf-8" for text ones.
+	ContentEncoding string
+	// Marshal encodes a TaskMessage.
+	Marshal func(v interface{}) ([]byte, error)
+	// Unmarshal decodes a TaskMessage, used by verifyPublish.
+	Unmarshal func(data []byte, v interface{}) error
+}
+
+var (
+	serializersMu sync.RWMutex
+	serializers   = make(map[string]Serializer)
+)
+
+// RegisterSerializer makes a serializer available to the serializer options under the given name,
+// for integrators building k6 with their own serializers. It's meant to be called from an init function.
+// It panics if the name is a built-in serializer or is already registered, like modules.Register.
+func RegisterSerializer(name string, serializer Serializer) {
+	switch name {
+	case serializerJSON, serializerProtobuf, serializerMsgpack, serializerYAML:
+		panic(fmt.Sprintf("celery serializer %q is a built-in serializer", name))
+	}
+	if serializer.ContentType == "" || serializer.Marshal == nil || serializer.Unmarshal == nil {
+		panic(fmt.Sprintf("celery serializer %q requires a content type, Marshal and Unmarshal", name))
+	}
+
+	serializersMu.Lock()
+	defer serializersMu.Unlock()
+	if _, ok := serializers[name]; ok {
+		panic(fmt.Sprintf("celery serializer %q is already registered", name))
+	}
+	serializers[name] = serializer
+}
+
+// registeredSerializer returns the serializer registered under the given name.
+func registeredSerializer(name string) (Serializer, bool) {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	serializer, ok := serializers[name]
+	return serializer, ok
+}
+
+// registeredSerializerFor returns the registered serializer of a content type.
+func registeredSerializerFor(contentType string) (Serializer, bool) {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	for _, serializer := range serializers {
+		if serializer.ContentType == contentType {
+			return serializer, true
+		}
+	}
+	return Serializer{}, false
+}
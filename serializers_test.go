@@ -0,0 +1,56 @@
+package celery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSerializer is registered once for the test binary, as registering twice panics.
+const testSerializer = "test-json"
+
+func init() {
+	RegisterSerializer(testSerializer, Serializer{
+		ContentType:     "application/x-test-json",
+		ContentEncoding: "utf-8",
+		Marshal:         json.Marshal,
+		Unmarshal:       json.Unmarshal,
+	})
+}
+
+func TestRegisteredSerializer(t *testing.T) {
+	t.Parallel()
+
+	vu, client, mr := newTestClient(t, `{serializer: "test-json"}`)
+	_, err := client.Delay("tasks.add", vu.VU.Runtime().ToValue(1))
+	require.NoError(t, err)
+	_, err = client.DelayWithOptions("tasks.add", vu.VU.Runtime().ToValue(map[string]interface{}{"serializer": "json"}))
+	require.NoError(t, err)
+
+	messages, tasks := queuedMessages(t, mr, "celery")
+	require.Len(t, messages, 2)
+	assert.Equal(t, "application/x-test-json", messages[0].ContentType)
+	assert.Equal(t, "utf-8", messages[0].ContentEncoding)
+	assert.Equal(t, []interface{}{float64(1)}, tasks[0].Args)
+	assert.Equal(t, "application/json", messages[1].ContentType)
+
+	err = vu.newClientError(t, "redis://"+mr.Addr(), `{serializer: "unregistered"}`)
+	assert.ErrorContains(t, err, `celery serializer "unregistered" is not supported`)
+}
+
+func TestRegisterSerializerPanics(t *testing.T) {
+	t.Parallel()
+
+	serializer := Serializer{ContentType: "application/x-other", Marshal: json.Marshal, Unmarshal: json.Unmarshal}
+	assert.PanicsWithValue(t, `celery serializer "test-json" is already registered`, func() {
+		RegisterSerializer(testSerializer, serializer)
+	})
+	assert.PanicsWithValue(t, `celery serializer "msgpack" is a built-in serializer`, func() {
+		RegisterSerializer(serializerMsgpack, serializer)
+	})
+	assert.PanicsWithValue(t, `celery serializer "incomplete" requires a content type, Marshal and Unmarshal`, func() {
+		RegisterSerializer("incomplete", Serializer{ContentType: "application/x-incomplete"})
+	})
+}